@@ -1,73 +1,61 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"path"
+	"reflect"
 	"time"
 
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/events"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/logging"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/metrics"
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/service"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/alldebrid"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
 	"github.com/ensingerphilipp/premiumizearr-nova/pkg/premiumizeme"
-	"github.com/orandin/lumberjackrus"
-	log "github.com/sirupsen/logrus"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/realdebrid"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/torbox"
+	"github.com/sirupsen/logrus"
 )
 
+var log = logging.Subsystem("app")
+
+// logBufferSize is the number of log entries kept for the log viewer of the web ui
+const logBufferSize = 1000
+
 type App struct {
-	config             config.Config
-	premiumizemeClient premiumizeme.Premiumizeme
-	transferManager    service.TransferManagerService
-	directoryWatcher   service.DirectoryWatcherService
-	webServer          service.WebServerService
-	arrsManager        service.ArrsManagerService
+	config           config.Config
+	cloudAccounts    []*service.CloudAccount
+	transferManager  service.TransferManagerService
+	directoryWatcher service.DirectoryWatcherService
+	webServer        service.WebServerService
+	arrsManager      service.ArrsManagerService
+	accountService   service.AccountService
+	logBuffer        *logging.RingBuffer
+	events           *events.Bus
 }
 
 // Makes go vet error - prevents copies
 func (app *App) Lock()   {}
 func (app *App) UnLock() {}
 
-func (app *App) Start(logLevel string, configFile string, loggingDirectory string) error {
-	//Setup static login
-	lvl, err := log.ParseLevel(logLevel)
+// Start runs the daemon until ctx is done and then stops the services in reverse order within the ShutdownTimeoutSeconds.
+// The services derive their contexts from ctx but are canceled by their Stop, so uploads stop before downloads are checkpointed.
+func (app *App) Start(ctx context.Context, logLevel string, configFile string, loggingDirectory string) error {
+	lvl, err := logrus.ParseLevel(logLevel)
 	if err != nil {
-		log.Errorf("error flag not recognized, defaulting to Info!!", err)
-		lvl = log.InfoLevel
+		log.Errorf("error flag not recognized, defaulting to Info!! %s", err)
+		lvl = logrus.InfoLevel
 	}
-	log.SetLevel(lvl)
-	hook, err := lumberjackrus.NewHook(
-		&lumberjackrus.LogFile{
-			Filename:   path.Join(loggingDirectory, "premiumizearr.general.log"),
-			MaxSize:    100,
-			MaxBackups: 1,
-			MaxAge:     1,
-			Compress:   false,
-			LocalTime:  false,
-		},
-		log.InfoLevel,
-		&log.TextFormatter{},
-		&lumberjackrus.LogFileOpts{
-			log.InfoLevel: &lumberjackrus.LogFile{
-				Filename:   path.Join(loggingDirectory, "premiumizearr.info.log"),
-				MaxSize:    100,
-				MaxBackups: 1,
-				MaxAge:     1,
-				Compress:   false,
-				LocalTime:  false,
-			},
-			log.ErrorLevel: &lumberjackrus.LogFile{
-				Filename:   path.Join(loggingDirectory, "premiumizearr.error.log"),
-				MaxSize:    100,   // optional
-				MaxBackups: 1,     // optional
-				MaxAge:     1,     // optional
-				Compress:   false, // optional
-				LocalTime:  false, // optional
-			},
-		},
-	)
-
+	// Log with the defaults until the logging section of the config is loaded
+	err = logging.Init(loggingDirectory, lvl)
 	if err != nil {
-		panic(err)
+		return err
 	}
-
-	log.AddHook(hook)
+	app.logBuffer = logging.NewRingBuffer(logBufferSize)
+	logging.AddHook(app.logBuffer)
 
 	log.Info("---------- Starting premiumizearr daemon ----------")
 	log.Info("")
@@ -80,35 +68,131 @@ func (app *App) Start(logLevel string, configFile string, loggingDirectory strin
 		panic(err)
 	}
 
+	err = logging.Configure(app.config.Logging)
+	if err != nil {
+		log.Error(err)
+	}
+
+	// The services are canceled one after another by stopServices instead of all at once by ctx
+	serviceCtx := context.WithoutCancel(ctx)
+
 	// Initialisation
-	app.premiumizemeClient = premiumizeme.NewPremiumizemeClient(app.config.PremiumizemeAPIKey)
+	app.cloudAccounts = newCloudAccounts(&app.config)
+	app.events = events.NewBus()
 
 	app.transferManager = service.TransferManagerService{}.New()
 	app.directoryWatcher = service.DirectoryWatcherService{}.New()
 	app.webServer = service.WebServerService{}.New()
 	app.arrsManager = service.ArrsManagerService{}.New()
+	app.accountService = service.AccountService{}.New()
 
 	// Initialise Services
 	app.arrsManager.Init(&app.config)
-	app.directoryWatcher.Init(&app.premiumizemeClient, &app.config)
+	app.directoryWatcher.Init(serviceCtx, app.cloudAccounts, &app.arrsManager, &app.transferManager, app.events, &app.config)
+	app.accountService.Init(serviceCtx, app.cloudAccounts, &app.config)
 
 	// Must come after arrsManager
-	app.transferManager.Init(&app.premiumizemeClient, &app.arrsManager, &app.config)
+	app.transferManager.Init(serviceCtx, app.cloudAccounts, &app.arrsManager, app.events, &app.config)
 	// Must come after transfer, arrManager and directory
-	app.webServer.Init(&app.transferManager, &app.directoryWatcher, &app.arrsManager, &app.config)
+	app.webServer.Init(&app.transferManager, &app.directoryWatcher, &app.arrsManager, &app.accountService, app.logBuffer, app.events, &app.config)
+
+	// Started in this order and stopped in reverse, uploads stop first and the web server last
+	services := []service.Service{
+		&app.arrsManager,
+		&app.accountService,
+		&app.webServer,
+		&app.transferManager,
+		&app.directoryWatcher,
+	}
+	for i, s := range services {
+		err = s.Start()
+		if err != nil {
+			log.Errorf("Error starting services: %s", err)
+			app.stopServices(services[:i])
+			return err
+		}
+	}
 
-	app.arrsManager.Start()
-	app.webServer.Start()
-	app.directoryWatcher.Start()
 	//Block until the program is terminated
-	app.transferManager.Run(15 * time.Second)
+	<-ctx.Done()
+	log.Info("Received shutdown signal, stopping services...")
+	app.stopServices(services)
 
+	log.Info("---------- Stopped premiumizearr daemon ----------")
 	return nil
 }
 
+// stopServices stops services in reverse order, giving all of them together ShutdownTimeoutSeconds
+func (app *App) stopServices(services []service.Service) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(app.config.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	for i := len(services) - 1; i >= 0; i-- {
+		err := services[i].Stop(ctx)
+		if err != nil {
+			log.Errorf("Error stopping service: %s", err)
+		}
+	}
+}
+
+// newCloudAccounts creates the accounts of the provider selected in the config
+func newCloudAccounts(c *config.Config) []*service.CloudAccount {
+	switch c.Provider {
+	case config.ProviderRealDebrid:
+		log.Info("Using real-debrid as cloud downloader")
+		client := realdebrid.NewRealDebridClient(c.RealDebridAPIKey, loadOwnedTransfers(c))
+		return []*service.CloudAccount{service.NewCloudAccount(string(c.Provider), &client)}
+	case config.ProviderAllDebrid:
+		log.Info("Using alldebrid as cloud downloader")
+		client := alldebrid.NewAllDebridClient(c.AllDebridAPIKey, loadOwnedTransfers(c))
+		return []*service.CloudAccount{service.NewCloudAccount(string(c.Provider), &client)}
+	case config.ProviderTorBox:
+		log.Info("Using torbox as cloud downloader")
+		client := torbox.NewTorBoxClient(c.TorBoxAPIKey, loadOwnedTransfers(c))
+		return []*service.CloudAccount{service.NewCloudAccount(string(c.Provider), &client)}
+	case config.ProviderPremiumizeme, "":
+	default:
+		log.Warnf("Unknown provider %s, falling back to premiumize.me", c.Provider)
+	}
+
+	log.Infof("Using %d premiumize.me accounts as cloud downloader", len(c.PremiumizemeAccounts))
+	accounts := make([]*service.CloudAccount, 0, len(c.PremiumizemeAccounts))
+	for i, account := range c.PremiumizemeAccounts {
+		name := account.Name
+		if name == "" {
+			name = fmt.Sprintf("Account %d", i+1)
+		}
+		client := premiumizeme.NewPremiumizemeClient(account.APIKey, premiumizeme.WithRequestObserver(metrics.ObservePremiumizemeRequest))
+		accounts = append(accounts, service.NewCloudAccount(name, &client))
+	}
+	return accounts
+}
+
+// loadOwnedTransfers loads the transfers the provider selected in the config created,
+// the provider is left without owned transfers if they cannot be read so nothing of the user is touched
+func loadOwnedTransfers(c *config.Config) *clouddownloader.OwnedTransfers {
+	filePath := path.Join(c.GetConfigDirectory(), string(c.Provider)+".transfers.json")
+	owned, err := clouddownloader.LoadOwnedTransfers(filePath)
+	if err != nil {
+		log.Errorf("Error loading transfers created on %s from %s: %s", c.Provider, filePath, err)
+	}
+	return owned
+}
+
 func (app *App) ConfigUpdatedCallback(currentConfig config.Config, newConfig config.Config) {
+	if !reflect.DeepEqual(currentConfig.Logging, newConfig.Logging) {
+		log.Info("Logging config changed, applying it...")
+		err := logging.Configure(newConfig.Logging)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+	if currentConfig.Provider != newConfig.Provider || !reflect.DeepEqual(currentConfig.PremiumizemeAccounts, newConfig.PremiumizemeAccounts) {
+		log.Warn("Cloud downloader accounts changed, restart premiumizearr for the change to take effect")
+	}
 	app.transferManager.ConfigUpdatedCallback(currentConfig, newConfig)
 	app.directoryWatcher.ConfigUpdatedCallback(currentConfig, newConfig)
 	app.webServer.ConfigUpdatedCallback(currentConfig, newConfig)
 	app.arrsManager.ConfigUpdatedCallback(currentConfig, newConfig)
+	app.accountService.ConfigUpdatedCallback(currentConfig, newConfig)
 }
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/utils"
 )
@@ -29,14 +33,27 @@ func main() {
 	var loggingDirectory string
 
 	//Parse flags
-	fmt.Println(asciiArt)
+	fmt.Print(asciiArt)
 	fmt.Println("Premiumizearr-Nova Version: 1.4.5")
 	flag.StringVar(&logLevel, "log", utils.EnvOrDefault("PREMIUMIZEARR_LOG_LEVEL", "info"), "Logging level: \n \tinfo,debug,trace")
 	flag.StringVar(&configFile, "config", utils.EnvOrDefault("PREMIUMIZEARR_CONFIG_DIR_PATH", "./"), "The directory the config.yml is located in")
 	flag.StringVar(&loggingDirectory, "logging-dir", utils.EnvOrDefault("PREMIUMIZEARR_LOGGING_DIR_PATH", "./"), "The directory logs are to be written to")
 	flag.Parse()
 
+	// The root context of all services, canceled on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// A second signal kills the daemon without waiting for the services to stop
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	App := &App{}
-	App.Start(logLevel, configFile, loggingDirectory)
+	err := App.Start(ctx, logLevel, configFile, loggingDirectory)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "premiumizearr stopped with an error: %s\n", err)
+		os.Exit(1)
+	}
 
 }
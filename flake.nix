@@ -29,7 +29,6 @@
         name = "premiumizearr-nova";
         src = ./.;
         vendorHash = "sha256-1Ka6FxsUlqqD3rksXCO57KdJ2Ryzc78XBlRf/nSfDfA=";
-        propagatedBuildInputs = [ pkgs.coreutils pkgs.wget ];
         # Patch paths to use static web assets from Nix store
         patchPhase = ''
           ${pkgs.gnused}/bin/sed -i 's|"./static/index.html"|"${web-static}/index.html"|' internal/service/web_service.go
@@ -1,11 +1,17 @@
 package arr
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
-	"github.com/ensingerphilipp/premiumizearr-nova/pkg/premiumizeme"
-	log "github.com/sirupsen/logrus"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/metrics"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/sirupsen/logrus"
+	"golift.io/starr"
 	"golift.io/starr/sonarr"
 )
 
@@ -35,17 +41,26 @@ func (arr *SonarrArr) GetHistory() (sonarr.History, error) {
 		arr.History = his
 		arr.LastUpdate = time.Now()
 		arr.LastUpdateCount = his.TotalRecords
-		log.Debugf("[Sonarr] [%s]: Updated history, next update in %d seconds", arr.Name, arr.Config.ArrHistoryUpdateIntervalSeconds)
+		arr.logger().Debugf("Updated history, next update in %d seconds", arr.Config.ArrHistoryUpdateIntervalSeconds)
 	}
 
-	log.Tracef("[Sonarr] [%s]: Returning from GetHistory", arr.Name)
+	arr.logger().Trace("Returning from GetHistory")
 	return *arr.History, nil
 }
 
 func (arr *SonarrArr) MarkHistoryItemAsFailed(id int64) error {
 	arr.ClientMutex.Lock()
 	defer arr.ClientMutex.Unlock()
-	return arr.Client.Fail(id)
+	err := arr.Client.Fail(id)
+	if err == nil {
+		metrics.ArrFailuresMarked.WithLabelValues(arr.Name).Inc()
+	}
+	return err
+}
+
+// logger attaches the arr to the entries logged for it
+func (arr *SonarrArr) logger() *logrus.Entry {
+	return log.WithFields(logrus.Fields{"arr": arr.Name, "arr_type": "Sonarr"})
 }
 
 func (arr *SonarrArr) GetArrName() string {
@@ -55,12 +70,12 @@ func (arr *SonarrArr) GetArrName() string {
 // Functions
 
 func (arr *SonarrArr) HistoryContains(name string) (int64, bool) {
-	log.Tracef("Sonarr [%s]: Checking history for %s", arr.Name, name)
+	arr.logger().WithField("release", name).Trace("Checking history")
 	his, err := arr.GetHistory()
 	if err != nil {
 		return 0, false
 	}
-	log.Tracef("Sonarr [%s]: Got History, now Locking History", arr.Name)
+	arr.logger().Trace("Got History, now Locking History")
 	arr.HistoryMutex.Lock()
 	defer arr.HistoryMutex.Unlock()
 
@@ -69,12 +84,12 @@ func (arr *SonarrArr) HistoryContains(name string) (int64, bool) {
 			return item.ID, true
 		}
 	}
-	log.Tracef("Sonarr [%s]: %s Not in History", name)
+	arr.logger().WithField("release", name).Trace("Not in History")
 
 	return -1, false
 }
 
-func (arr *SonarrArr) HandleErrorTransfer(transfer *premiumizeme.Transfer, arrID int64, pm *premiumizeme.Premiumizeme) error {
+func (arr *SonarrArr) HandleErrorTransfer(ctx context.Context, transfer *clouddownloader.Transfer, arrID int64, cd clouddownloader.CloudDownloaderInterface) error {
 	his, err := arr.GetHistory()
 	if err != nil {
 		return fmt.Errorf("failed to get history from sonarr: %+v", err)
@@ -92,9 +107,9 @@ func (arr *SonarrArr) HandleErrorTransfer(transfer *premiumizeme.Transfer, arrID
 				if err != nil {
 					return fmt.Errorf("failed to blacklist item in sonarr: %+v", err)
 				}
-				err = pm.DeleteTransfer(transfer.ID)
+				err = cd.DeleteTransfer(ctx, transfer.ID)
 				if err != nil {
-					return fmt.Errorf("failed to delete transfer from premiumize.me: %+v", err)
+					return fmt.Errorf("failed to delete transfer from cloud downloader: %+v", err)
 				}
 				complete = true
 				break
@@ -103,11 +118,49 @@ func (arr *SonarrArr) HandleErrorTransfer(transfer *premiumizeme.Transfer, arrID
 	}
 
 	if !complete {
-		err := pm.DeleteTransfer(transfer.ID)
+		err := cd.DeleteTransfer(ctx, transfer.ID)
 		if err != nil {
-			return fmt.Errorf("failed to delete transfer from premiumize.me: %+v", err)
+			return fmt.Errorf("failed to delete transfer from cloud downloader: %+v", err)
 		}
 	}
 
 	return nil
 }
+
+// ImportDownload tells Sonarr to import the completed download at path, returning the id of the started command
+func (arr *SonarrArr) ImportDownload(ctx context.Context, path string, importMode config.ImportMode) (int64, error) {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(downloadedScanCommand{
+		Name:       "DownloadedEpisodesScan",
+		Path:       path,
+		ImportMode: string(importMode),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	arr.ClientMutex.Lock()
+	defer arr.ClientMutex.Unlock()
+
+	var output sonarr.CommandResponse
+	err = arr.Client.PostInto(ctx, starr.Request{URI: commandPath, Body: &body}, &output)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send DownloadedEpisodesScan command to sonarr: %+v", err)
+	}
+
+	arr.logger().WithFields(logrus.Fields{"command_id": output.ID, "path": path}).Debug("Started DownloadedEpisodesScan command")
+	return output.ID, nil
+}
+
+// GetCommandStatus returns the status and message of a previously started command
+func (arr *SonarrArr) GetCommandStatus(ctx context.Context, id int64) (string, string, error) {
+	arr.ClientMutex.Lock()
+	defer arr.ClientMutex.Unlock()
+
+	var output sonarr.CommandResponse
+	err := arr.Client.GetInto(ctx, starr.Request{URI: fmt.Sprintf("%s/%d", commandPath, id)}, &output)
+	if err != nil {
+		return "", "", err
+	}
+	return output.Status, output.Message, nil
+}
@@ -1,17 +1,21 @@
 package arr
 
 import (
+	"context"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/logging"
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/utils"
-	"github.com/ensingerphilipp/premiumizearr-nova/pkg/premiumizeme"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
 	"golift.io/starr/radarr"
 	"golift.io/starr/sonarr"
 )
 
+var log = logging.Subsystem("arr")
+
 func CompareFileNamesFuzzy(a, b string) bool {
 	//Strip file extension
 	a = utils.StripDownloadTypesExtention(a)
@@ -41,10 +45,21 @@ func CompareFileNamesFuzzy(a, b string) bool {
 type IArr interface {
 	HistoryContains(string) (int64, bool)
 	MarkHistoryItemAsFailed(int64) error
-	HandleErrorTransfer(*premiumizeme.Transfer, int64, *premiumizeme.Premiumizeme) error
+	HandleErrorTransfer(context.Context, *clouddownloader.Transfer, int64, clouddownloader.CloudDownloaderInterface) error
 	GetArrName() string
+	ImportDownload(ctx context.Context, path string, importMode config.ImportMode) (int64, error)
+	GetCommandStatus(context.Context, int64) (string, string, error)
+}
+
+// downloadedScanCommand asks an arr to import a completed download, starr's CommandRequest has no path or import mode
+type downloadedScanCommand struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	ImportMode string `json:"importMode"`
 }
 
+const commandPath = "/command"
+
 type SonarrArr struct {
 	Name                 string
 	ClientMutex          sync.Mutex
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidBandwidthWindow = errors.New("invalid bandwidth schedule window")
+
+// ValidateBandwidthSchedule returns an error wrapping ErrInvalidBandwidthWindow for the first window with invalid hours or weekdays
+func ValidateBandwidthSchedule(schedule []BandwidthScheduleWindow) error {
+	for _, w := range schedule {
+		err := w.validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w BandwidthScheduleWindow) validate() error {
+	if w.StartHour < 0 || w.StartHour > 23 || w.EndHour < 0 || w.EndHour > 24 {
+		return fmt.Errorf("%w: %s has hours %d-%d, StartHour must be 0-23 and EndHour 0-24", ErrInvalidBandwidthWindow, w.Name, w.StartHour, w.EndHour)
+	}
+	if w.SpeedLimit < 0 {
+		return fmt.Errorf("%w: %s has a negative speed limit", ErrInvalidBandwidthWindow, w.Name)
+	}
+	for _, weekday := range w.Weekdays {
+		if !isWeekday(weekday) {
+			return fmt.Errorf("%w: %s has unknown weekday %q", ErrInvalidBandwidthWindow, w.Name, weekday)
+		}
+	}
+	return nil
+}
+
+func isWeekday(name string) bool {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) || strings.EqualFold(name, day.String()[:3]) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetActiveBandwidthWindow returns the first window of the BandwidthSchedule containing now, nil if none does
+func (c *Config) GetActiveBandwidthWindow(now time.Time) *BandwidthScheduleWindow {
+	for i := range c.BandwidthSchedule {
+		if c.BandwidthSchedule[i].Contains(now) {
+			window := c.BandwidthSchedule[i]
+			return &window
+		}
+	}
+	return nil
+}
+
+// Contains reports whether now falls inside the window, the window is expected to be validated when the config is loaded
+func (w BandwidthScheduleWindow) Contains(now time.Time) bool {
+	hour := now.Hour()
+	if w.StartHour < w.EndHour {
+		return hour >= w.StartHour && hour < w.EndHour && w.runsOn(now.Weekday())
+	}
+
+	// The window runs past midnight, the part after midnight belongs to the previous day
+	if hour >= w.StartHour {
+		return w.runsOn(now.Weekday())
+	}
+	if hour < w.EndHour {
+		return w.runsOn(now.AddDate(0, 0, -1).Weekday())
+	}
+	return false
+}
+
+func (w BandwidthScheduleWindow) runsOn(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, weekday := range w.Weekdays {
+		if strings.EqualFold(weekday, day.String()) || strings.EqualFold(weekday, day.String()[:3]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// monday returns the time at hour on Monday 2024-01-01, hours past 23 fall on the following days
+func monday(hour int) time.Time {
+	return time.Date(2024, 1, 1, hour, 30, 0, 0, time.UTC)
+}
+
+func TestBandwidthScheduleWindowContains(t *testing.T) {
+	daytime := BandwidthScheduleWindow{Name: "daytime", StartHour: 8, EndHour: 18}
+	night := BandwidthScheduleWindow{Name: "night", StartHour: 22, EndHour: 6}
+	mondayNight := BandwidthScheduleWindow{Name: "monday night", Weekdays: []string{"Monday"}, StartHour: 22, EndHour: 6}
+	weekend := BandwidthScheduleWindow{Name: "weekend", Weekdays: []string{"sat", "SUNDAY"}, StartHour: 0, EndHour: 24}
+	allDay := BandwidthScheduleWindow{Name: "all day", StartHour: 0, EndHour: 0}
+
+	tests := []struct {
+		window BandwidthScheduleWindow
+		now    time.Time
+		want   bool
+	}{
+		{daytime, monday(7), false},
+		{daytime, monday(8), true},
+		{daytime, monday(17), true},
+		{daytime, monday(18), false},
+		{night, monday(21), false},
+		{night, monday(22), true},
+		{night, monday(23), true},
+		{night, monday(24), true},
+		{night, monday(29), true},
+		{night, monday(30), false},
+		{night, monday(5), true},
+		// The part after midnight belongs to the day the window started on
+		{mondayNight, monday(23), true},
+		{mondayNight, monday(24 + 5), true},
+		{mondayNight, monday(5), false},
+		{mondayNight, monday(24 + 23), false},
+		{weekend, monday(5 * 24), true},
+		{weekend, monday(6*24 + 23), true},
+		{weekend, monday(7 * 24), false},
+		{weekend, monday(12), false},
+		{allDay, monday(0), true},
+		{allDay, monday(12), true},
+	}
+	for _, test := range tests {
+		if got := test.window.Contains(test.now); got != test.want {
+			t.Errorf("%s.Contains(%s) = %t, want %t", test.window.Name, test.now.Format("Mon 15:04"), got, test.want)
+		}
+	}
+}
+
+func TestGetActiveBandwidthWindow(t *testing.T) {
+	config := Config{BandwidthSchedule: []BandwidthScheduleWindow{
+		{Name: "night", StartHour: 22, EndHour: 6, Paused: true},
+		{Name: "all day", StartHour: 0, EndHour: 24, SpeedLimit: 10},
+	}}
+
+	if window := config.GetActiveBandwidthWindow(monday(23)); window == nil || window.Name != "night" {
+		t.Errorf("expected the first matching window night, got %+v", window)
+	}
+	if window := config.GetActiveBandwidthWindow(monday(12)); window == nil || window.Name != "all day" {
+		t.Errorf("expected window all day, got %+v", window)
+	}
+	if window := (&Config{}).GetActiveBandwidthWindow(monday(12)); window != nil {
+		t.Errorf("expected no window without a schedule, got %+v", window)
+	}
+}
+
+func TestValidateBandwidthSchedule(t *testing.T) {
+	tests := []struct {
+		window BandwidthScheduleWindow
+		valid  bool
+	}{
+		{BandwidthScheduleWindow{StartHour: 0, EndHour: 24}, true},
+		{BandwidthScheduleWindow{StartHour: 23, EndHour: 0}, true},
+		{BandwidthScheduleWindow{Weekdays: []string{"Mon", "tuesday"}, StartHour: 1, EndHour: 2}, true},
+		{BandwidthScheduleWindow{StartHour: 24, EndHour: 2}, false},
+		{BandwidthScheduleWindow{StartHour: -1, EndHour: 2}, false},
+		{BandwidthScheduleWindow{StartHour: 1, EndHour: 25}, false},
+		{BandwidthScheduleWindow{StartHour: 1, EndHour: 2, SpeedLimit: -1}, false},
+		{BandwidthScheduleWindow{Weekdays: []string{"Funday"}, StartHour: 1, EndHour: 2}, false},
+	}
+	for _, test := range tests {
+		err := ValidateBandwidthSchedule([]BandwidthScheduleWindow{{StartHour: 1, EndHour: 2}, test.window})
+		if test.valid && err != nil {
+			t.Errorf("%+v: unexpected error %s", test.window, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidBandwidthWindow) {
+			t.Errorf("%+v: expected ErrInvalidBandwidthWindow, got %v", test.window, err)
+		}
+	}
+}
+
+func TestUpdateConfigRejectsInvalidSchedule(t *testing.T) {
+	config := defaultConfig()
+	config.altConfigLocation = t.TempDir()
+	config.appCallback = func(oldConfig Config, newConfig Config) {
+		t.Error("callback called for a rejected config")
+	}
+
+	newConfig := defaultConfig()
+	newConfig.BandwidthSchedule = []BandwidthScheduleWindow{{Name: "broken", StartHour: 30, EndHour: 2}}
+	err := config.UpdateConfig(newConfig)
+	if !errors.Is(err, ErrInvalidBandwidthWindow) {
+		t.Fatalf("expected ErrInvalidBandwidthWindow, got %v", err)
+	}
+	if len(config.BandwidthSchedule) != 0 {
+		t.Errorf("rejected schedule was applied: %+v", config.BandwidthSchedule)
+	}
+}
+
+func TestLoadConfigRejectsInvalidSchedule(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(path.Join(dir, "config.yaml"), []byte("BandwidthSchedule:\n  - Name: broken\n    StartHour: 8\n    EndHour: 30\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = loadConfigFromDisk(dir)
+	if err != ErrInvalidConfigFile {
+		t.Errorf("expected ErrInvalidConfigFile, got %v", err)
+	}
+}
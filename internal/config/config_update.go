@@ -1,6 +1,12 @@
 package config
 
-func (c *Config) UpdateConfig(_newConfig Config) {
+// UpdateConfig replaces the config with _newConfig and saves it, an invalid _newConfig is rejected and leaves the config unchanged
+func (c *Config) UpdateConfig(_newConfig Config) error {
+	err := ValidateBandwidthSchedule(_newConfig.BandwidthSchedule)
+	if err != nil {
+		return err
+	}
+
 	oldConfig := *c
 
 	//move private fields over
@@ -10,4 +16,5 @@ func (c *Config) UpdateConfig(_newConfig Config) {
 
 	c.appCallback(oldConfig, *c)
 	c.Save()
+	return nil
 }
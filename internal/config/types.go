@@ -1,6 +1,10 @@
 package config
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/logging"
+)
 
 var (
 	ErrInvalidConfigFile      = errors.New("invalid Config File")
@@ -19,6 +23,34 @@ const (
 	Radarr ArrType = "Radarr"
 )
 
+// Provider selects the cloud downloader transfers are sent to
+type Provider string
+
+const (
+	ProviderPremiumizeme Provider = "premiumize.me"
+	ProviderRealDebrid   Provider = "real-debrid"
+	ProviderAllDebrid    Provider = "alldebrid"
+	ProviderTorBox       Provider = "torbox"
+)
+
+// ImportMode tells the arrs whether to move or copy completed downloads when importing them
+type ImportMode string
+
+const (
+	ImportModeMove     ImportMode = "Move"
+	ImportModeCopy     ImportMode = "Copy"
+	ImportModeDisabled ImportMode = "Disabled"
+)
+
+// CacheCheckMode decides what happens to blackhole releases the cloud downloader has not cached
+type CacheCheckMode string
+
+const (
+	CacheCheckDisabled       CacheCheckMode = "Disabled"
+	CacheCheckLog            CacheCheckMode = "Log"
+	CacheCheckRejectUncached CacheCheckMode = "RejectUncached"
+)
+
 type ArrConfig struct {
 	Name   string  `yaml:"Name" json:"Name"`
 	URL    string  `yaml:"URL" json:"URL"`
@@ -26,12 +58,47 @@ type ArrConfig struct {
 	Type   ArrType `yaml:"Type" json:"Type"`
 }
 
+// PremiumizemeAccount is a premiumize.me account transfers can be uploaded to
+type PremiumizemeAccount struct {
+	Name   string `yaml:"Name" json:"Name"`
+	APIKey string `yaml:"APIKey" json:"APIKey"`
+}
+
+// BandwidthScheduleWindow overrides DownloadSpeedLimit during a range of hours on the given weekdays
+type BandwidthScheduleWindow struct {
+	Name string `yaml:"Name" json:"Name"`
+	// Weekdays the window starts on (e.g. "Monday"), empty means every day
+	Weekdays []string `yaml:"Weekdays" json:"Weekdays"`
+	// StartHour is inclusive, EndHour is exclusive, a window with EndHour <= StartHour runs past midnight
+	StartHour int `yaml:"StartHour" json:"StartHour"`
+	EndHour   int `yaml:"EndHour" json:"EndHour"`
+	// SpeedLimit in Megabytes per second, 0 means unlimited
+	SpeedLimit int  `yaml:"SpeedLimit" json:"SpeedLimit"`
+	Paused     bool `yaml:"Paused" json:"Paused"`
+}
+
 type Config struct {
 	altConfigLocation string
 	appCallback       AppCallback
 
-	//PremiumizemeAPIKey string with yaml and json tag
-	PremiumizemeAPIKey string `yaml:"PremiumizemeAPIKey" json:"PremiumizemeAPIKey"`
+	// PremiumizemeAPIKey is only read to move it into PremiumizemeAccounts
+	PremiumizemeAPIKey string `yaml:"PremiumizemeAPIKey,omitempty" json:"PremiumizemeAPIKey,omitempty"`
+
+	// PremiumizemeAccounts are all used at once, uploads go to the account with the fewest running transfers
+	PremiumizemeAccounts []PremiumizemeAccount `yaml:"PremiumizemeAccounts" json:"PremiumizemeAccounts"`
+	// FairUseUploadPauseThreshold pauses uploads to an account once it used this percentage of its fair use limit, 0 disables it
+	FairUseUploadPauseThreshold int `yaml:"FairUseUploadPauseThreshold" json:"FairUseUploadPauseThreshold"`
+
+	// Provider is the cloud downloader used for transfers, changing it requires a restart
+	Provider         Provider `yaml:"Provider" json:"Provider"`
+	RealDebridAPIKey string   `yaml:"RealDebridAPIKey" json:"RealDebridAPIKey"`
+	AllDebridAPIKey  string   `yaml:"AllDebridAPIKey" json:"AllDebridAPIKey"`
+	TorBoxAPIKey     string   `yaml:"TorBoxAPIKey" json:"TorBoxAPIKey"`
+
+	// CacheCheckMode checks the cache before uploading .torrent and .magnet files, RejectUncached marks uncached releases as failed in the arr
+	CacheCheckMode CacheCheckMode `yaml:"CacheCheckMode" json:"CacheCheckMode"`
+	// DirectDownloadCached downloads cached releases right away instead of creating a transfer, premiumize.me only
+	DirectDownloadCached bool `yaml:"DirectDownloadCached" json:"DirectDownloadCached"`
 
 	Arrs []ArrConfig `yaml:"Arrs" json:"Arrs"`
 
@@ -49,5 +116,19 @@ type Config struct {
 	SimultaneousDownloads int `yaml:"SimultaneousDownloads" json:"SimultaneousDownloads"`
 	DownloadSpeedLimit    int `yaml:"DownloadSpeedLimit" json:"DownloadSpeedLimit"`
 
+	BandwidthSchedule []BandwidthScheduleWindow `yaml:"BandwidthSchedule" json:"BandwidthSchedule"`
+
+	DownloadSegmentsPerFile      int `yaml:"DownloadSegmentsPerFile" json:"DownloadSegmentsPerFile"`
+	DownloadSegmentMinimumSizeMB int `yaml:"DownloadSegmentMinimumSizeMB" json:"DownloadSegmentMinimumSizeMB"`
+
 	ArrHistoryUpdateIntervalSeconds int `yaml:"ArrHistoryUpdateIntervalSeconds" json:"ArrHistoryUpdateIntervalSeconds"`
+
+	// ArrImportMode triggers an import in the matching arr as soon as a download completes, unless Disabled
+	ArrImportMode ImportMode `yaml:"ArrImportMode" json:"ArrImportMode"`
+
+	// ShutdownTimeoutSeconds is how long running uploads and downloads get to stop on SIGINT or SIGTERM before the daemon exits anyway
+	ShutdownTimeoutSeconds int `yaml:"ShutdownTimeoutSeconds" json:"ShutdownTimeoutSeconds"`
+
+	// Logging configures the log format, levels and log file rotation, changes apply without a restart
+	Logging logging.Config `yaml:"Logging" json:"Logging"`
 }
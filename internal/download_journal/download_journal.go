@@ -0,0 +1,229 @@
+package download_journal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/logging"
+)
+
+var log = logging.Subsystem("journal")
+
+// LoadJournal reads the journal from path, a missing file results in an empty journal.
+func LoadJournal(path string) (*Journal, error) {
+	j := &Journal{
+		path:        path,
+		Folders:     make(map[string]*FolderEntry),
+		fileFolders: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		log.Tracef("No download journal found at %s, starting empty", path)
+		return j, nil
+	}
+	if err != nil {
+		return j, err
+	}
+
+	err = json.Unmarshal(data, j)
+	if err != nil {
+		j.Folders = make(map[string]*FolderEntry)
+		return j, err
+	}
+
+	if j.Folders == nil {
+		j.Folders = make(map[string]*FolderEntry)
+	}
+	for folderKey, folder := range j.Folders {
+		if folder.Files == nil {
+			folder.Files = make(map[string]*FileEntry)
+		}
+		for fileID := range folder.Files {
+			j.fileFolders[key(folder.Account, fileID)] = folderKey
+		}
+	}
+
+	log.Debugf("Loaded download journal with %d folders from %s", len(j.Folders), path)
+	return j, nil
+}
+
+// key identifies the item itemID of account in the journal
+func key(account string, itemID string) string {
+	return account + "/" + itemID
+}
+
+// AddFolder records the start of a folder download of account, an existing entry is kept as is.
+func (j *Journal) AddFolder(account string, itemID string, name string, path string) {
+	j.addFolder(&FolderEntry{
+		ItemID:  itemID,
+		Name:    name,
+		Account: account,
+		Path:    path,
+	})
+}
+
+// AddDirectFolder records the start of a direct download of account queued from the blackhole file sourcePath,
+// an existing entry is kept as is.
+func (j *Journal) AddDirectFolder(account string, itemID string, name string, path string, sourcePath string) {
+	j.addFolder(&FolderEntry{
+		ItemID:     itemID,
+		Name:       name,
+		Account:    account,
+		Path:       path,
+		SourcePath: sourcePath,
+	})
+}
+
+func (j *Journal) addFolder(folder *FolderEntry) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	folderKey := key(folder.Account, folder.ItemID)
+	if _, ok := j.Folders[folderKey]; ok {
+		return
+	}
+
+	folder.Added = time.Now()
+	folder.Files = make(map[string]*FileEntry)
+	j.Folders[folderKey] = folder
+	j.save()
+}
+
+// AddFile records the start of a file download within the folder folderID of account.
+func (j *Journal) AddFile(account string, folderID string, itemID string, name string, path string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	folderKey := key(account, folderID)
+	folder, ok := j.Folders[folderKey]
+	if !ok {
+		log.Warnf("Cannot journal file %s, folder %s of account %s is not journaled", name, folderID, account)
+		return
+	}
+	if _, ok := folder.Files[itemID]; ok {
+		return
+	}
+
+	folder.Files[itemID] = &FileEntry{
+		ItemID: itemID,
+		Name:   name,
+		Path:   path,
+	}
+	j.fileFolders[key(account, itemID)] = folderKey
+	j.save()
+}
+
+// UpdateProgress records the bytes completed of a file of account without writing to disk, see Checkpoint.
+func (j *Journal) UpdateProgress(account string, fileID string, bytesCompleted int64) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if file := j.getFile(account, fileID); file != nil {
+		file.BytesCompleted = bytesCompleted
+	}
+}
+
+// CompleteFile marks a file of account as completely downloaded.
+func (j *Journal) CompleteFile(account string, fileID string, bytesCompleted int64) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if file := j.getFile(account, fileID); file != nil {
+		file.BytesCompleted = bytesCompleted
+		file.Completed = true
+		j.save()
+	}
+}
+
+// IsFileCompleted reports whether a file of account has been completely downloaded.
+func (j *Journal) IsFileCompleted(account string, fileID string) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	file := j.getFile(account, fileID)
+	return file != nil && file.Completed
+}
+
+// RemoveFolder removes a folder of account and all of its files from the journal.
+func (j *Journal) RemoveFolder(account string, itemID string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	folderKey := key(account, itemID)
+	folder, ok := j.Folders[folderKey]
+	if !ok {
+		return
+	}
+	for fileID := range folder.Files {
+		delete(j.fileFolders, key(account, fileID))
+	}
+	delete(j.Folders, folderKey)
+	j.save()
+}
+
+// GetFolders returns a copy of all journaled folders.
+func (j *Journal) GetFolders() []FolderEntry {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	folders := make([]FolderEntry, 0, len(j.Folders))
+	for _, folder := range j.Folders {
+		folders = append(folders, *folder)
+	}
+	return folders
+}
+
+// ContainsPath reports whether path is the download directory of a journaled folder.
+func (j *Journal) ContainsPath(path string) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	path = filepath.Clean(path)
+	for _, folder := range j.Folders {
+		if filepath.Clean(folder.Path) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Checkpoint writes the journal including all progress updates to disk.
+func (j *Journal) Checkpoint() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.save()
+}
+
+func (j *Journal) getFile(account string, fileID string) *FileEntry {
+	folderKey, ok := j.fileFolders[key(account, fileID)]
+	if !ok {
+		return nil
+	}
+	return j.Folders[folderKey].Files[fileID]
+}
+
+// save writes the journal to a temporary file and renames it into place, must be called with the mutex held.
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		log.Errorf("Failed to marshal download journal: %+v", err)
+		return err
+	}
+
+	tmpPath := j.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		log.Errorf("Failed to write download journal %s: %+v", tmpPath, err)
+		return err
+	}
+
+	err = os.Rename(tmpPath, j.path)
+	if err != nil {
+		log.Errorf("Failed to replace download journal %s: %+v", j.path, err)
+		return err
+	}
+	return nil
+}
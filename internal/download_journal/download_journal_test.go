@@ -0,0 +1,43 @@
+package download_journal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalKeepsAccountsApart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	j, err := LoadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j.AddFolder("first", "1", "release", "/downloads/.partial/release")
+	j.AddFolder("second", "1", "other release", "/downloads/.partial/other release")
+	j.AddFile("first", "1", "file", "file.mkv", "/downloads/.partial/release/file.mkv")
+	j.AddFile("second", "1", "file", "file.mkv", "/downloads/.partial/other release/file.mkv")
+	j.CompleteFile("first", "file", 10)
+
+	if len(j.GetFolders()) != 2 {
+		t.Fatalf("journal has %d folders, want 2", len(j.GetFolders()))
+	}
+	if !j.IsFileCompleted("first", "file") {
+		t.Error("file of first account is not completed")
+	}
+	if j.IsFileCompleted("second", "file") {
+		t.Error("completing the file of the first account completed the file of the second account")
+	}
+
+	j.RemoveFolder("first", "1")
+	reloaded, err := LoadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders := reloaded.GetFolders()
+	if len(folders) != 1 || folders[0].Account != "second" {
+		t.Errorf("reloaded journal has folders %+v, want the folder of the second account", folders)
+	}
+	if reloaded.IsFileCompleted("second", "file") {
+		t.Error("file of the second account is completed after reload")
+	}
+}
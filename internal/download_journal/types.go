@@ -0,0 +1,42 @@
+package download_journal
+
+import (
+	"sync"
+	"time"
+)
+
+// FileEntry records the progress of a single file download.
+type FileEntry struct {
+	// ItemID is the id of the file on premiumize.me
+	ItemID         string `json:"item_id"`
+	Name           string `json:"name"`
+	Path           string `json:"path"`
+	BytesCompleted int64  `json:"bytes_completed"`
+	Completed      bool   `json:"completed"`
+}
+
+// FolderEntry records a folder download and all files started within it, including subfolders.
+type FolderEntry struct {
+	// ItemID is the id of the folder on premiumize.me
+	ItemID string `json:"item_id"`
+	Name   string `json:"name"`
+	// Account is the name of the cloud downloader account the folder is stored on
+	Account string `json:"account"`
+	// Path is the local directory the folder is downloaded into
+	Path string `json:"path"`
+	// SourcePath is the blackhole file of a direct download, which has no folder on the cloud downloader
+	SourcePath string    `json:"source_path,omitempty"`
+	Added      time.Time `json:"added"`
+	// Files keyed by their ItemID, they are stored on the account of the folder
+	Files map[string]*FileEntry `json:"files"`
+}
+
+// Journal persists in-progress downloads to disk so they can be resumed after a restart.
+type Journal struct {
+	mutex sync.Mutex
+	path  string
+	// Folders keyed by their account and ItemID, see key, as ids are only unique within an account
+	Folders map[string]*FolderEntry `json:"folders"`
+	// fileFolders maps the keys of files to the key of the folder they belong to
+	fileFolders map[string]string
+}
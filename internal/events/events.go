@@ -0,0 +1,139 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// subscriberBufferSize is the number of events a subscriber can fall behind before events are dropped for it
+const subscriberBufferSize = 256
+
+// Type is the kind of state change an event reports, it is the event name of the event stream of the web server
+type Type string
+
+const (
+	// BlackholeFileQueued is published with a BlackholeFile when a blackhole file or submission is added to the upload queue
+	BlackholeFileQueued Type = "blackhole_file_queued"
+	// BlackholeFileUploaded is published with a BlackholeFile when a blackhole file was uploaded or handed over for direct download
+	BlackholeFileUploaded Type = "blackhole_file_uploaded"
+	// TransferStatusChanged is published with a TransferStatus when a transfer appears or its status changes
+	TransferStatusChanged Type = "transfer_status_changed"
+	// DownloadProgress is published with a []Progress of all running downloads every tick while downloads are running
+	DownloadProgress Type = "download_progress"
+	// DownloadCompleted is published with a Download when a download was moved into the downloads directory
+	DownloadCompleted Type = "download_completed"
+	// Failed is published with a Failure when an upload or download failed, it is not named error as
+	// that is the name of the event browsers fire when the event stream disconnects
+	Failed Type = "failed"
+)
+
+type BlackholeFile struct {
+	Name        string `json:"name"`
+	Category    string `json:"category,omitempty"`
+	Account     string `json:"account,omitempty"`
+	QueueLength int    `json:"queue_length"`
+}
+
+type TransferStatus struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Account        string  `json:"account"`
+	Status         string  `json:"status"`
+	PreviousStatus string  `json:"previous_status"`
+	Progress       float64 `json:"progress"`
+	Message        string  `json:"message"`
+}
+
+type Progress struct {
+	Name           string  `json:"name"`
+	Progress       string  `json:"progress"`
+	Speed          string  `json:"speed"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+}
+
+type Download struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type Failure struct {
+	// Source is the step that failed, upload or download
+	Source  string `json:"source"`
+	Name    string `json:"name"`
+	Account string `json:"account,omitempty"`
+	Message string `json:"message"`
+}
+
+// Event is a state change published on a Bus
+type Event struct {
+	// ID increases with every event published on the bus
+	ID   uint64      `json:"id"`
+	Time time.Time   `json:"time"`
+	Type Type        `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Bus passes the events published by the services on to its subscribers
+type Bus struct {
+	mutex       sync.Mutex
+	lastID      uint64
+	subscribers map[chan Event]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+// Publish sends an event of eventType with data to all subscribers without waiting for them,
+// a slow subscriber loses events instead of holding up the service. Publishing on a nil Bus does nothing
+func (b *Bus) Publish(eventType Type, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lastID++
+	e := Event{
+		ID:   b.lastID,
+		Time: time.Now(),
+		Type: eventType,
+		Data: data,
+	}
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- e:
+		default:
+		}
+	}
+}
+
+// HasSubscribers reports whether anyone receives the published events, so expensive events can be skipped otherwise
+func (b *Bus) HasSubscribers() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.subscribers) > 0
+}
+
+// Subscribe returns a channel receiving all events published afterwards,
+// unsubscribe has to be called once the channel is no longer read
+func (b *Bus) Subscribe() (ch <-chan Event, unsubscribe func()) {
+	subscriber := make(chan Event, subscriberBufferSize)
+
+	b.mutex.Lock()
+	b.subscribers[subscriber] = struct{}{}
+	b.mutex.Unlock()
+
+	return subscriber, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.subscribers, subscriber)
+	}
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	bus := NewBus()
+	if bus.HasSubscribers() {
+		t.Error("new bus has subscribers")
+	}
+	bus.Publish(DownloadCompleted, Download{Name: "before"})
+
+	ch, unsubscribe := bus.Subscribe()
+	if !bus.HasSubscribers() {
+		t.Error("subscriber is not reported")
+	}
+	bus.Publish(DownloadCompleted, Download{Name: "after"})
+
+	select {
+	case e := <-ch:
+		download, ok := e.Data.(Download)
+		if e.ID != 2 || e.Type != DownloadCompleted || !ok || download.Name != "after" {
+			t.Errorf("unexpected event %+v", e)
+		}
+		if e.Time.IsZero() {
+			t.Error("event has no time")
+		}
+	default:
+		t.Fatal("subscriber did not receive the event")
+	}
+	select {
+	case e := <-ch:
+		t.Errorf("subscriber received an event published before subscribing: %+v", e)
+	default:
+	}
+
+	unsubscribe()
+	if bus.HasSubscribers() {
+		t.Error("unsubscribed subscriber is still reported")
+	}
+	bus.Publish(DownloadCompleted, Download{Name: "unsubscribed"})
+	select {
+	case e := <-ch:
+		t.Errorf("unsubscribed subscriber received %+v", e)
+	default:
+	}
+}
+
+func TestPublishToAllSubscribers(t *testing.T) {
+	bus := NewBus()
+	first, unsubscribeFirst := bus.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := bus.Subscribe()
+
+	bus.Publish(Failed, Failure{Source: "upload", Name: "Some.Release"})
+	unsubscribeSecond()
+	bus.Publish(Failed, Failure{Source: "download", Name: "Some.Release"})
+
+	if len(first) != 2 {
+		t.Errorf("expected 2 events for the first subscriber, got %d", len(first))
+	}
+	if len(second) != 1 {
+		t.Errorf("expected 1 event for the unsubscribed subscriber, got %d", len(second))
+	}
+}
+
+func TestPublishDropsForSlowSubscriber(t *testing.T) {
+	bus := NewBus()
+	slow, unsubscribeSlow := bus.Subscribe()
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := bus.Subscribe()
+	defer unsubscribeFast()
+
+	// Publish must not block once a subscriber is subscriberBufferSize events behind
+	received := 0
+	for i := 0; i < subscriberBufferSize+10; i++ {
+		bus.Publish(DownloadProgress, []Progress{})
+		<-fast
+		received++
+	}
+	if received != subscriberBufferSize+10 {
+		t.Errorf("fast subscriber lost events, received %d", received)
+	}
+	if len(slow) != subscriberBufferSize {
+		t.Fatalf("expected %d buffered events, got %d", subscriberBufferSize, len(slow))
+	}
+	if e := <-slow; e.ID != 1 {
+		t.Errorf("expected the oldest events to be kept, got ID %d", e.ID)
+	}
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(DownloadCompleted, Download{Name: "nil"})
+	if bus.HasSubscribers() {
+		t.Error("nil bus has subscribers")
+	}
+}
+
+func TestConcurrentPublishAndSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				bus.Publish(TransferStatusChanged, TransferStatus{ID: "transfer"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				ch, unsubscribe := bus.Subscribe()
+				bus.HasSubscribers()
+				select {
+				case <-ch:
+				default:
+				}
+				unsubscribe()
+			}
+		}()
+	}
+	wg.Wait()
+
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+	bus.Publish(TransferStatusChanged, TransferStatus{ID: "last"})
+	if e := <-ch; e.ID != 401 {
+		t.Errorf("expected event IDs to increase without gaps, got %d after 400 events", e.ID)
+	}
+}
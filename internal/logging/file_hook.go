@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// fileHook writes info and error entries to their own files and everything else to the general file
+type fileHook struct {
+	formatter logrus.Formatter
+	general   *lumberjack.Logger
+	info      *lumberjack.Logger
+	errors    *lumberjack.Logger
+}
+
+func newFileHook(formatter logrus.Formatter, general *lumberjack.Logger, info *lumberjack.Logger, errors *lumberjack.Logger) *fileHook {
+	return &fileHook{
+		formatter: formatter,
+		general:   general,
+		info:      info,
+		errors:    errors,
+	}
+}
+
+func newFileLogger(filename string, config FileConfig) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    config.MaxSizeMB,
+		MaxBackups: config.MaxBackups,
+		MaxAge:     config.MaxAgeDays,
+		Compress:   config.Compress,
+		LocalTime:  false,
+	}
+}
+
+func (h *fileHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *fileHook) Fire(entry *logrus.Entry) error {
+	msg, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	switch entry.Level {
+	case logrus.InfoLevel:
+		_, err = h.info.Write(msg)
+	case logrus.ErrorLevel:
+		_, err = h.errors.Write(msg)
+	default:
+		_, err = h.general.Write(msg)
+	}
+	return err
+}
+
+// Close closes the files, they are opened again by the next write
+func (h *fileHook) Close() error {
+	return errors.Join(h.general.Close(), h.info.Close(), h.errors.Close())
+}
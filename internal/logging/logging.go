@@ -0,0 +1,247 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SubsystemField is the field holding the subsystem an entry was logged by, its level can be overridden by Config.SubsystemLevels
+const SubsystemField = "subsystem"
+
+// Format is the format of the console and file log output
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// FileConfig configures the rotation of a log file
+type FileConfig struct {
+	// MaxSizeMB is the size the file is rotated at
+	MaxSizeMB int `yaml:"MaxSizeMB" json:"MaxSizeMB"`
+	// MaxBackups is the number of rotated files kept
+	MaxBackups int `yaml:"MaxBackups" json:"MaxBackups"`
+	// MaxAgeDays is the number of days rotated files are kept, 0 keeps them regardless of their age
+	MaxAgeDays int  `yaml:"MaxAgeDays" json:"MaxAgeDays"`
+	Compress   bool `yaml:"Compress" json:"Compress"`
+}
+
+// Config configures the log output, it is applied again whenever it changes
+type Config struct {
+	Format Format `yaml:"Format" json:"Format"`
+	// Level is the level of all subsystems without override, empty uses the level passed with -log
+	Level string `yaml:"Level" json:"Level"`
+	// SubsystemLevels overrides Level by subsystem, e.g. trace for arr
+	SubsystemLevels map[string]string `yaml:"SubsystemLevels" json:"SubsystemLevels"`
+
+	// GeneralFile receives all entries except info and error, which go to InfoFile and ErrorFile
+	GeneralFile FileConfig `yaml:"GeneralFile" json:"GeneralFile"`
+	InfoFile    FileConfig `yaml:"InfoFile" json:"InfoFile"`
+	ErrorFile   FileConfig `yaml:"ErrorFile" json:"ErrorFile"`
+}
+
+// DefaultConfig returns the configuration used until the config file is loaded
+func DefaultConfig() Config {
+	file := FileConfig{
+		MaxSizeMB:  100,
+		MaxBackups: 1,
+		MaxAgeDays: 1,
+		Compress:   false,
+	}
+	return Config{
+		Format:          FormatText,
+		Level:           "",
+		SubsystemLevels: map[string]string{},
+		GeneralFile:     file,
+		InfoFile:        file,
+		ErrorFile:       file,
+	}
+}
+
+// Subsystem returns the logger of a subsystem, every package logs through one stored in its log variable
+func Subsystem(name string) *logrus.Entry {
+	return logrus.WithField(SubsystemField, name)
+}
+
+// logging is the state behind the filters, it is kept outside of logrus so the filters never wait for the logrus mutex
+type logging struct {
+	mutex           sync.RWMutex
+	directory       string
+	defaultLevel    logrus.Level
+	config          Config
+	level           logrus.Level
+	subsystemLevels map[string]logrus.Level
+	files           *fileHook
+	hooks           []logrus.Hook
+}
+
+var state = &logging{
+	defaultLevel:    logrus.InfoLevel,
+	level:           logrus.InfoLevel,
+	subsystemLevels: map[string]logrus.Level{},
+}
+
+// Init writes the log files to directory and uses defaultLevel unless Config.Level is set, then applies DefaultConfig
+func Init(directory string, defaultLevel logrus.Level) error {
+	state.mutex.Lock()
+	state.directory = directory
+	state.defaultLevel = defaultLevel
+	state.mutex.Unlock()
+
+	return Configure(DefaultConfig())
+}
+
+// Configure applies config, invalid levels are reported as error and replaced by the default level
+func Configure(config Config) error {
+	var errs []error
+
+	state.mutex.RLock()
+	level := state.defaultLevel
+	state.mutex.RUnlock()
+	if config.Level != "" {
+		parsed, err := logrus.ParseLevel(config.Level)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid log level %s: %w", config.Level, err))
+		} else {
+			level = parsed
+		}
+	}
+
+	// The logger has to let through the most verbose level, the filters drop everything else
+	loggerLevel := level
+	subsystemLevels := make(map[string]logrus.Level, len(config.SubsystemLevels))
+	for subsystem, subsystemLevel := range config.SubsystemLevels {
+		parsed, err := logrus.ParseLevel(subsystemLevel)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid log level %s for subsystem %s: %w", subsystemLevel, subsystem, err))
+			continue
+		}
+		subsystemLevels[subsystem] = parsed
+		if parsed > loggerLevel {
+			loggerLevel = parsed
+		}
+	}
+
+	var formatter logrus.Formatter
+	var fileFormatter logrus.Formatter
+	switch config.Format {
+	case FormatJSON:
+		formatter = &logrus.JSONFormatter{}
+		fileFormatter = &logrus.JSONFormatter{}
+	case FormatText, "":
+		formatter = &logrus.TextFormatter{}
+		fileFormatter = &logrus.TextFormatter{DisableColors: true}
+	default:
+		errs = append(errs, fmt.Errorf("invalid log format %s, using %s", config.Format, FormatText))
+		formatter = &logrus.TextFormatter{}
+		fileFormatter = &logrus.TextFormatter{DisableColors: true}
+	}
+
+	state.mutex.Lock()
+	var closed *fileHook
+	if state.files == nil || state.config.Format != config.Format ||
+		!reflect.DeepEqual(
+			[]FileConfig{state.config.GeneralFile, state.config.InfoFile, state.config.ErrorFile},
+			[]FileConfig{config.GeneralFile, config.InfoFile, config.ErrorFile}) {
+		closed = state.files
+		state.files = newFileHook(fileFormatter,
+			newFileLogger(path.Join(state.directory, "premiumizearr.general.log"), config.GeneralFile),
+			newFileLogger(path.Join(state.directory, "premiumizearr.info.log"), config.InfoFile),
+			newFileLogger(path.Join(state.directory, "premiumizearr.error.log"), config.ErrorFile),
+		)
+	}
+	state.config = config
+	state.level = level
+	state.subsystemLevels = subsystemLevels
+	hooks := state.levelHooks()
+	state.mutex.Unlock()
+
+	logger := logrus.StandardLogger()
+	logger.SetFormatter(&filterFormatter{formatter: formatter})
+	logger.SetLevel(loggerLevel)
+	logger.ReplaceHooks(hooks)
+
+	if closed != nil {
+		err := closed.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error closing log files: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("error configuring logging: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// AddHook adds hook to the logger, it only receives the entries passing the level filters and is kept by Configure
+func AddHook(hook logrus.Hook) {
+	state.mutex.Lock()
+	state.hooks = append(state.hooks, hook)
+	hooks := state.levelHooks()
+	state.mutex.Unlock()
+
+	logrus.StandardLogger().ReplaceHooks(hooks)
+}
+
+// levelHooks wraps the file hook and the added hooks in filters, must be called with the mutex held
+func (l *logging) levelHooks() logrus.LevelHooks {
+	hooks := make(logrus.LevelHooks)
+	if l.files != nil {
+		hooks.Add(&filterHook{hook: l.files})
+	}
+	for _, hook := range l.hooks {
+		hooks.Add(&filterHook{hook: hook})
+	}
+	return hooks
+}
+
+// Enabled reports whether entries of level logged by subsystem pass the level filters
+func Enabled(subsystem string, level logrus.Level) bool {
+	state.mutex.RLock()
+	defer state.mutex.RUnlock()
+
+	if subsystemLevel, ok := state.subsystemLevels[subsystem]; ok {
+		return level <= subsystemLevel
+	}
+	return level <= state.level
+}
+
+func entryEnabled(entry *logrus.Entry) bool {
+	subsystem, _ := entry.Data[SubsystemField].(string)
+	return Enabled(subsystem, entry.Level)
+}
+
+// filterFormatter drops the entries not passing the level filters from the console output
+type filterFormatter struct {
+	formatter logrus.Formatter
+}
+
+func (f *filterFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if !entryEnabled(entry) {
+		return nil, nil
+	}
+	return f.formatter.Format(entry)
+}
+
+// filterHook only fires hook for the entries passing the level filters
+type filterHook struct {
+	hook logrus.Hook
+}
+
+func (h *filterHook) Levels() []logrus.Level {
+	return h.hook.Levels()
+}
+
+func (h *filterHook) Fire(entry *logrus.Entry) error {
+	if !entryEnabled(entry) {
+		return nil
+	}
+	return h.hook.Fire(entry)
+}
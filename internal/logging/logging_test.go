@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordingHook records the messages of the entries it was fired with
+type recordingHook struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (h *recordingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *recordingHook) Fire(entry *logrus.Entry) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.messages = append(h.messages, entry.Message)
+	return nil
+}
+
+func (h *recordingHook) getMessages() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]string(nil), h.messages...)
+}
+
+// initTestLogging initialises logging into a temporary directory and discards the console output
+func initTestLogging(t *testing.T) {
+	logrus.SetOutput(io.Discard)
+	err := Init(t.TempDir(), logrus.InfoLevel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Configure(DefaultConfig())
+	})
+}
+
+func TestEnabled(t *testing.T) {
+	initTestLogging(t)
+
+	config := DefaultConfig()
+	config.Level = "warning"
+	config.SubsystemLevels = map[string]string{"arr": "trace", "web": "error"}
+	err := Configure(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		subsystem string
+		level     logrus.Level
+		want      bool
+	}{
+		{"arr", logrus.TraceLevel, true},
+		{"arr", logrus.ErrorLevel, true},
+		{"web", logrus.WarnLevel, false},
+		{"web", logrus.ErrorLevel, true},
+		{"transfers", logrus.InfoLevel, false},
+		{"transfers", logrus.WarnLevel, true},
+		{"", logrus.DebugLevel, false},
+		{"", logrus.ErrorLevel, true},
+	}
+	for _, test := range tests {
+		if got := Enabled(test.subsystem, test.level); got != test.want {
+			t.Errorf("Enabled(%q, %s) = %t, want %t", test.subsystem, test.level, got, test.want)
+		}
+	}
+	if level := logrus.GetLevel(); level != logrus.TraceLevel {
+		t.Errorf("logger has to let through the most verbose subsystem level, got %s", level)
+	}
+}
+
+func TestConfigureInvalidLevels(t *testing.T) {
+	initTestLogging(t)
+
+	config := DefaultConfig()
+	config.Level = "loud"
+	config.SubsystemLevels = map[string]string{"arr": "chatty", "web": "debug"}
+	err := Configure(config)
+	if err == nil {
+		t.Fatal("expected an error for invalid levels")
+	}
+
+	// Invalid levels fall back to the default level, valid overrides are still applied
+	if Enabled("", logrus.DebugLevel) || !Enabled("", logrus.InfoLevel) {
+		t.Error("invalid level did not fall back to the default level")
+	}
+	if Enabled("arr", logrus.DebugLevel) {
+		t.Error("invalid subsystem level was applied")
+	}
+	if !Enabled("web", logrus.DebugLevel) {
+		t.Error("valid subsystem level was not applied")
+	}
+}
+
+func TestFilterHook(t *testing.T) {
+	initTestLogging(t)
+	hook := &recordingHook{}
+	AddHook(hook)
+
+	config := DefaultConfig()
+	config.SubsystemLevels = map[string]string{"arr": "debug"}
+	err := Configure(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Subsystem("arr").Debug("arr debug")
+	Subsystem("web").Debug("web debug")
+	Subsystem("web").Info("web info")
+	logrus.Debug("no subsystem debug")
+
+	messages := hook.getMessages()
+	if len(messages) != 2 || messages[0] != "arr debug" || messages[1] != "web info" {
+		t.Errorf("expected only the entries passing the filters, got %v", messages)
+	}
+
+	// Added hooks are kept when the configuration is applied again
+	err = Configure(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Subsystem("arr").Debug("arr debug after reset")
+	Subsystem("arr").Info("arr info after reset")
+	if messages := hook.getMessages(); len(messages) != 3 || messages[2] != "arr info after reset" {
+		t.Errorf("hook lost or subsystem level kept after Configure, got %v", messages)
+	}
+}
+
+func TestConfigureWhileLogging(t *testing.T) {
+	initTestLogging(t)
+	hook := &recordingHook{}
+	AddHook(hook)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Subsystem("arr").Debug("debug")
+				Subsystem("web").Info("info")
+			}
+		}()
+	}
+	for _, level := range []string{"debug", "info", "trace", "warning"} {
+		config := DefaultConfig()
+		config.SubsystemLevels = map[string]string{"arr": level}
+		err := Configure(config)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+
+	if messages := hook.getMessages(); len(messages) < 400 {
+		t.Errorf("expected at least the 400 info entries, got %d", len(messages))
+	}
+}
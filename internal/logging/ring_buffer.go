@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// subscriberBufferSize is the number of entries a subscriber can fall behind before entries are dropped for it
+const subscriberBufferSize = 256
+
+// Entry is a log entry kept by RingBuffer
+type Entry struct {
+	// ID increases with every entry, so clients can resume after the last entry they received
+	ID        uint64            `json:"id"`
+	Time      time.Time         `json:"time"`
+	Level     string            `json:"level"`
+	Subsystem string            `json:"subsystem"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+
+	level logrus.Level
+}
+
+// Matches reports whether the entry is of level or more severe and was logged by subsystem, an empty subsystem matches all
+func (e Entry) Matches(level logrus.Level, subsystem string) bool {
+	return e.level <= level && (subsystem == "" || e.Subsystem == subsystem)
+}
+
+// RingBuffer is a hook keeping the last entries in memory and passing new entries on to its subscribers
+type RingBuffer struct {
+	mutex       sync.Mutex
+	entries     []Entry
+	next        int
+	lastID      uint64
+	subscribers map[chan Entry]struct{}
+}
+
+// NewRingBuffer returns a RingBuffer keeping the last size entries, it is added to the logger with AddHook
+func NewRingBuffer(size int) *RingBuffer {
+	return &RingBuffer{
+		entries:     make([]Entry, 0, size),
+		subscribers: map[chan Entry]struct{}{},
+	}
+}
+
+func (b *RingBuffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (b *RingBuffer) Fire(entry *logrus.Entry) error {
+	e := Entry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+		level:   entry.Level,
+	}
+	for key, value := range entry.Data {
+		if key == SubsystemField {
+			e.Subsystem, _ = value.(string)
+			continue
+		}
+		if e.Fields == nil {
+			e.Fields = make(map[string]string, len(entry.Data))
+		}
+		e.Fields[key] = fmt.Sprint(value)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lastID++
+	e.ID = b.lastID
+	if len(b.entries) < cap(b.entries) {
+		b.entries = append(b.entries, e)
+	} else if cap(b.entries) > 0 {
+		b.entries[b.next] = e
+		b.next = (b.next + 1) % cap(b.entries)
+	}
+
+	// Fire runs while the logger is locked, so a slow subscriber loses entries instead of blocking all logging
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- e:
+		default:
+		}
+	}
+	return nil
+}
+
+// Entries returns the kept entries after the entry with the ID after that match level and subsystem, oldest first
+func (b *RingBuffer) Entries(after uint64, level logrus.Level, subsystem string) []Entry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.matching(after, level, subsystem)
+}
+
+// Subscribe returns the kept entries like Entries and a channel receiving all entries logged afterwards,
+// unsubscribe has to be called once the channel is no longer read
+func (b *RingBuffer) Subscribe(after uint64, level logrus.Level, subsystem string) (entries []Entry, ch <-chan Entry, unsubscribe func()) {
+	subscriber := make(chan Entry, subscriberBufferSize)
+
+	b.mutex.Lock()
+	entries = b.matching(after, level, subsystem)
+	b.subscribers[subscriber] = struct{}{}
+	b.mutex.Unlock()
+
+	return entries, subscriber, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.subscribers, subscriber)
+	}
+}
+
+// matching must be called with the mutex held
+func (b *RingBuffer) matching(after uint64, level logrus.Level, subsystem string) []Entry {
+	entries := make([]Entry, 0, len(b.entries))
+	for i := range b.entries {
+		e := b.entries[(b.next+i)%len(b.entries)]
+		if e.ID > after && e.Matches(level, subsystem) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
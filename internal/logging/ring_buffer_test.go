@@ -0,0 +1,183 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fire passes an entry of level logged by subsystem with message to buffer
+func fire(t *testing.T, buffer *RingBuffer, subsystem string, level logrus.Level, message string) {
+	entry := logrus.WithField(SubsystemField, subsystem)
+	entry.Level = level
+	entry.Message = message
+	err := buffer.Fire(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func messages(entries []Entry) []string {
+	result := make([]string, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e.Message)
+	}
+	return result
+}
+
+func TestRingBufferWrapsAround(t *testing.T) {
+	buffer := NewRingBuffer(3)
+	for i := 1; i <= 5; i++ {
+		fire(t, buffer, "arr", logrus.InfoLevel, fmt.Sprint(i))
+	}
+
+	entries := buffer.Entries(0, logrus.TraceLevel, "")
+	if got := fmt.Sprint(messages(entries)); got != "[3 4 5]" {
+		t.Errorf("expected the last 3 entries oldest first, got %s", got)
+	}
+	if entries[0].ID != 3 || entries[2].ID != 5 {
+		t.Errorf("expected IDs 3 to 5, got %d to %d", entries[0].ID, entries[2].ID)
+	}
+	if got := fmt.Sprint(messages(buffer.Entries(4, logrus.TraceLevel, ""))); got != "[5]" {
+		t.Errorf("expected the entries after ID 4, got %s", got)
+	}
+	if entries := buffer.Entries(5, logrus.TraceLevel, ""); len(entries) != 0 {
+		t.Errorf("expected no entries after the last ID, got %v", messages(entries))
+	}
+}
+
+func TestRingBufferEntries(t *testing.T) {
+	buffer := NewRingBuffer(10)
+	fire(t, buffer, "arr", logrus.DebugLevel, "arr debug")
+	fire(t, buffer, "arr", logrus.ErrorLevel, "arr error")
+	fire(t, buffer, "web", logrus.InfoLevel, "web info")
+	fire(t, buffer, "web", logrus.WarnLevel, "web warning")
+
+	tests := []struct {
+		level     logrus.Level
+		subsystem string
+		want      string
+	}{
+		{logrus.TraceLevel, "", "[arr debug arr error web info web warning]"},
+		{logrus.InfoLevel, "", "[arr error web info web warning]"},
+		{logrus.WarnLevel, "", "[arr error web warning]"},
+		{logrus.TraceLevel, "arr", "[arr debug arr error]"},
+		{logrus.InfoLevel, "web", "[web info web warning]"},
+		{logrus.ErrorLevel, "web", "[]"},
+		{logrus.TraceLevel, "transfers", "[]"},
+	}
+	for _, test := range tests {
+		if got := fmt.Sprint(messages(buffer.Entries(0, test.level, test.subsystem))); got != test.want {
+			t.Errorf("Entries(0, %s, %q) = %s, want %s", test.level, test.subsystem, got, test.want)
+		}
+	}
+}
+
+func TestRingBufferFields(t *testing.T) {
+	buffer := NewRingBuffer(1)
+	entry := Subsystem("transfers").WithField("id", 42)
+	entry.Level = logrus.InfoLevel
+	entry.Message = "transfer finished"
+	err := buffer.Fire(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := buffer.Entries(0, logrus.TraceLevel, "")
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Subsystem != "transfers" || e.Level != "info" || len(e.Fields) != 1 || e.Fields["id"] != "42" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestRingBufferSubscribe(t *testing.T) {
+	buffer := NewRingBuffer(10)
+	fire(t, buffer, "arr", logrus.InfoLevel, "before")
+
+	entries, ch, unsubscribe := buffer.Subscribe(0, logrus.InfoLevel, "")
+	if got := fmt.Sprint(messages(entries)); got != "[before]" {
+		t.Errorf("expected the kept entries on subscribe, got %s", got)
+	}
+
+	fire(t, buffer, "arr", logrus.DebugLevel, "after")
+	select {
+	case e := <-ch:
+		if e.Message != "after" || e.ID != 2 {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	default:
+		t.Error("subscriber did not receive the new entry")
+	}
+
+	unsubscribe()
+	fire(t, buffer, "arr", logrus.InfoLevel, "unsubscribed")
+	select {
+	case e := <-ch:
+		t.Errorf("unsubscribed subscriber received %+v", e)
+	default:
+	}
+}
+
+func TestRingBufferDropsForSlowSubscriber(t *testing.T) {
+	buffer := NewRingBuffer(1)
+	_, ch, unsubscribe := buffer.Subscribe(0, logrus.TraceLevel, "")
+	defer unsubscribe()
+
+	// Fire must not block once the subscriber is subscriberBufferSize entries behind
+	for i := 0; i < subscriberBufferSize+10; i++ {
+		fire(t, buffer, "arr", logrus.InfoLevel, fmt.Sprint(i))
+	}
+	if len(ch) != subscriberBufferSize {
+		t.Fatalf("expected %d buffered entries, got %d", subscriberBufferSize, len(ch))
+	}
+	if e := <-ch; e.ID != 1 {
+		t.Errorf("expected the oldest entries to be kept, got ID %d", e.ID)
+	}
+	fire(t, buffer, "arr", logrus.InfoLevel, "caught up")
+	if len(ch) != subscriberBufferSize {
+		t.Errorf("subscriber did not receive entries again after catching up")
+	}
+}
+
+func TestRingBufferConcurrentSubscribers(t *testing.T) {
+	buffer := NewRingBuffer(16)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				entry := Subsystem("arr")
+				entry.Level = logrus.InfoLevel
+				entry.Message = "entry"
+				if err := buffer.Fire(entry); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_, ch, unsubscribe := buffer.Subscribe(0, logrus.InfoLevel, "")
+				select {
+				case <-ch:
+				default:
+				}
+				buffer.Entries(0, logrus.InfoLevel, "arr")
+				unsubscribe()
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries := buffer.Entries(0, logrus.TraceLevel, "")
+	if len(entries) != 16 || entries[15].ID != 400 {
+		t.Errorf("expected the last 16 of 400 entries, got %d ending with ID %d", len(entries), entries[len(entries)-1].ID)
+	}
+}
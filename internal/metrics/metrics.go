@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "premiumizearr"
+
+var (
+	BlackholeFilesQueued = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blackhole_files_queued_total",
+		Help:      "Blackhole files and submissions added to the upload queue.",
+	})
+	BlackholeFilesUploaded = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blackhole_files_uploaded_total",
+		Help:      "Blackhole files uploaded as transfer or handed over for direct download.",
+	})
+	BlackholeFilesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blackhole_files_failed_total",
+		Help:      "Blackhole files that could not be uploaded, by reason.",
+	}, []string{"reason"})
+
+	PremiumizemeAPICalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "premiumizeme_api_calls_total",
+		Help:      "Calls to the premiumize.me api by endpoint and result, retries are counted once.",
+	}, []string{"endpoint", "result"})
+
+	DownloadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloaded_bytes_total",
+		Help:      "Bytes downloaded from the cloud downloaders.",
+	})
+
+	ArrFailuresMarked = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "arr_failures_marked_total",
+		Help:      "Releases marked as failed in the arrs, by arr.",
+	}, []string{"arr"})
+
+	PollDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "poll_duration_seconds",
+		Help:      "Duration of a poll of the transfers and downloads folders.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	})
+)
+
+var (
+	activeDownloadsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "active_downloads"),
+		"Files currently downloading.", nil, nil)
+	downloadSpeedDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "download_speed_bytes_per_second"),
+		"Combined speed of the running downloads.", nil, nil)
+	transfersDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "transfers"),
+		"Transfers on the cloud downloader accounts by status as of the last poll.", []string{"account", "status"}, nil)
+)
+
+// DownloadsFunc returns the number of running downloads and their combined speed in bytes per second
+type DownloadsFunc func() (int, float64)
+
+// downloadsCollector reads the running downloads when scraped, so the speed is never stale
+type downloadsCollector struct {
+	mutex     sync.Mutex
+	downloads DownloadsFunc
+}
+
+var downloads = &downloadsCollector{}
+
+// transferKey labels the transfers metric
+type transferKey struct {
+	account string
+	status  string
+}
+
+// transfersCollector reports the transfer counts of the last poll, they are replaced as a whole
+// so a scrape never sees the counts of a poll half updated
+type transfersCollector struct {
+	mutex  sync.Mutex
+	counts map[transferKey]int
+}
+
+var transfers = &transfersCollector{}
+
+func init() {
+	prometheus.MustRegister(downloads)
+	prometheus.MustRegister(transfers)
+}
+
+// SetDownloadsFunc sets the source of the active downloads and download speed metrics
+func SetDownloadsFunc(f DownloadsFunc) {
+	downloads.mutex.Lock()
+	defer downloads.mutex.Unlock()
+	downloads.downloads = f
+}
+
+func (c *downloadsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeDownloadsDesc
+	ch <- downloadSpeedDesc
+}
+
+func (c *downloadsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	f := c.downloads
+	c.mutex.Unlock()
+
+	active, speed := 0, 0.0
+	if f != nil {
+		active, speed = f()
+	}
+	ch <- prometheus.MustNewConstMetric(activeDownloadsDesc, prometheus.GaugeValue, float64(active))
+	ch <- prometheus.MustNewConstMetric(downloadSpeedDesc, prometheus.GaugeValue, speed)
+}
+
+// SetTransfers replaces the transfers metric with the number of transfers by account and status
+func SetTransfers(list []clouddownloader.Transfer) {
+	counts := make(map[transferKey]int)
+	for _, transfer := range list {
+		counts[transferKey{account: transfer.Account, status: transfer.Status}]++
+	}
+
+	transfers.mutex.Lock()
+	defer transfers.mutex.Unlock()
+	transfers.counts = counts
+}
+
+func (c *transfersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- transfersDesc
+}
+
+func (c *transfersCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	counts := c.counts
+	c.mutex.Unlock()
+
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(transfersDesc, prometheus.GaugeValue, float64(count), key.account, key.status)
+	}
+}
+
+// ObservePremiumizemeRequest counts a call to the premiumize.me api, it is passed to premiumizeme.WithRequestObserver
+func ObservePremiumizemeRequest(endpoint string, err error) {
+	PremiumizemeAPICalls.WithLabelValues(endpoint, result(err)).Inc()
+}
+
+// result turns err into a label value of bounded cardinality
+func result(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.Is(err, clouddownloader.ErrAuth):
+		return "auth_error"
+	case errors.Is(err, clouddownloader.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, clouddownloader.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, clouddownloader.ErrLimitReached):
+		return "limit_reached"
+	case errors.Is(err, clouddownloader.ErrDuplicate):
+		return "duplicate"
+	default:
+		return "error"
+	}
+}
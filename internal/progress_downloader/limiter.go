@@ -0,0 +1,166 @@
+package progress_downloader
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket limiting the number of bytes per second read through it.
+// A single Limiter can be shared between any number of concurrent downloads to cap their combined speed.
+type Limiter struct {
+	mutex          sync.Mutex
+	bytesPerSecond float64
+	tokens         float64
+	last           time.Time
+	paused         bool
+	// changed is closed and replaced whenever the limit changes to wake up waiting readers
+	changed chan struct{}
+}
+
+// NewLimiter creates a Limiter allowing bytesPerSecond, 0 or less means unlimited.
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	return &Limiter{
+		bytesPerSecond: float64(bytesPerSecond),
+		tokens:         float64(bytesPerSecond),
+		last:           time.Now(),
+		changed:        make(chan struct{}),
+	}
+}
+
+// SetLimit changes the limit to bytesPerSecond, 0 or less means unlimited.
+// Readers currently waiting on the Limiter pick up the new limit immediately.
+func (l *Limiter) SetLimit(bytesPerSecond int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.refill(time.Now())
+	l.bytesPerSecond = float64(bytesPerSecond)
+	if l.bytesPerSecond <= 0 {
+		l.tokens = 0
+	} else if l.tokens > l.bytesPerSecond {
+		l.tokens = l.bytesPerSecond
+	}
+
+	l.notify()
+}
+
+// SetPaused blocks all reads through the Limiter until it is unpaused.
+func (l *Limiter) SetPaused(paused bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.refill(time.Now())
+	l.paused = paused
+	l.notify()
+}
+
+// IsPaused reports whether reads through the Limiter are paused.
+func (l *Limiter) IsPaused() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.paused
+}
+
+// notify wakes up all waiting readers, must be called with the mutex held.
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// GetLimit returns the current limit in bytes per second, 0 means unlimited.
+func (l *Limiter) GetLimit() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return int64(l.bytesPerSecond)
+}
+
+// refill adds the tokens accumulated since the last refill, must be called with the mutex held.
+func (l *Limiter) refill(now time.Time) {
+	if l.bytesPerSecond > 0 && !l.paused {
+		l.tokens += now.Sub(l.last).Seconds() * l.bytesPerSecond
+		// Allow at most one second worth of burst
+		if l.tokens > l.bytesPerSecond {
+			l.tokens = l.bytesPerSecond
+		}
+	}
+	l.last = now
+}
+
+// WaitN blocks until n bytes may be read or the context is canceled.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	l.mutex.Lock()
+	taken := false
+
+	for {
+		// A zero wait blocks until the limit changes
+		var wait time.Duration
+		if !l.paused {
+			if l.bytesPerSecond <= 0 {
+				l.mutex.Unlock()
+				return nil
+			}
+
+			l.refill(time.Now())
+			if !taken {
+				// Take the tokens right away, going into debt if needed, and wait until the debt is paid off
+				l.tokens -= float64(n)
+				taken = true
+			}
+			if l.tokens >= 0 {
+				l.mutex.Unlock()
+				return nil
+			}
+			wait = max(time.Duration(-l.tokens/l.bytesPerSecond*float64(time.Second)), time.Millisecond)
+		}
+		changed := l.changed
+		l.mutex.Unlock()
+
+		changedBeforeTimeout, err := sleep(ctx, wait, changed)
+		if err != nil {
+			return err
+		}
+		if !changedBeforeTimeout {
+			return nil
+		}
+
+		l.mutex.Lock()
+	}
+}
+
+// sleep waits for wait to pass, or forever if wait is zero, and reports whether changed was closed before that
+func sleep(ctx context.Context, wait time.Duration, changed <-chan struct{}) (bool, error) {
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-timeout:
+		return false, nil
+	case <-changed:
+		return true, nil
+	}
+}
+
+// limitedReader throttles reads from reader using limiter.
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,147 @@
+package progress_downloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitAsync runs WaitN in the background and returns a channel receiving its result
+func waitAsync(ctx context.Context, limiter *Limiter, n int) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- limiter.WaitN(ctx, n)
+	}()
+	return done
+}
+
+func expectBlocked(t *testing.T, done <-chan error, what string) {
+	t.Helper()
+	select {
+	case err := <-done:
+		t.Fatalf("%s returned early: %v", what, err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func expectReturned(t *testing.T, done <-chan error, what string) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("%s: %s", what, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("%s did not return", what)
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	limiter := NewLimiter(0)
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		err := limiter.WaitN(context.Background(), 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unlimited reads were throttled for %s", elapsed)
+	}
+}
+
+func TestLimiterDebt(t *testing.T) {
+	limiter := NewLimiter(1000)
+
+	// The burst covers the first second, taking more than that goes into debt
+	start := time.Now()
+	err := limiter.WaitN(context.Background(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("read within the burst waited %s", elapsed)
+	}
+
+	start = time.Now()
+	err = limiter.WaitN(context.Background(), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("read of 200 bytes at 1000 bytes per second only waited %s", elapsed)
+	}
+
+	// A read larger than the burst is let through once its debt is paid off instead of blocking forever
+	start = time.Now()
+	err = limiter.WaitN(context.Background(), 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 3*time.Second {
+		t.Errorf("read of 1500 bytes at 1000 bytes per second waited %s", elapsed)
+	}
+}
+
+func TestLimiterPause(t *testing.T) {
+	limiter := NewLimiter(0)
+	limiter.SetPaused(true)
+	if !limiter.IsPaused() {
+		t.Fatal("limiter is not paused")
+	}
+
+	done := waitAsync(context.Background(), limiter, 1)
+	expectBlocked(t, done, "read while paused")
+
+	limiter.SetPaused(false)
+	expectReturned(t, done, "read after unpausing")
+}
+
+func TestLimiterPauseCanceled(t *testing.T) {
+	limiter := NewLimiter(1000)
+	limiter.SetPaused(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := waitAsync(ctx, limiter, 1)
+	expectBlocked(t, done, "read while paused")
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read did not return after cancel")
+	}
+}
+
+func TestLimiterWakesUpOnLimitChange(t *testing.T) {
+	// A debt of 10 seconds at the slow limit
+	limiter := NewLimiter(100)
+	err := limiter.WaitN(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := waitAsync(context.Background(), limiter, 1000)
+	expectBlocked(t, done, "read in debt")
+
+	limiter.SetLimit(0)
+	expectReturned(t, done, "read after removing the limit")
+	if limiter.GetLimit() != 0 {
+		t.Errorf("GetLimit = %d, want 0", limiter.GetLimit())
+	}
+
+	// Raising the limit shortens the wait of readers already in debt
+	limiter = NewLimiter(100)
+	err = limiter.WaitN(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done = waitAsync(context.Background(), limiter, 1000)
+	expectBlocked(t, done, "read in debt")
+
+	limiter.SetLimit(1 << 20)
+	expectReturned(t, done, "read after raising the limit")
+}
@@ -1,108 +1,285 @@
 package progress_downloader
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
-	"regexp"
+	"io"
+	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/dustin/go-humanize"
-	log "github.com/sirupsen/logrus"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/logging"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/metrics"
+)
+
+var log = logging.Subsystem("downloader")
+
+var (
+	ErrUnexpectedStatus = errors.New("unexpected http status")
+	ErrIncompleteBody   = errors.New("download ended before all bytes were received")
+)
+
+// httpClient has no overall timeout as downloads can take hours, cancellation is done through the context.
+var httpClient = &http.Client{}
+
+const (
+	copyBufferSize = 32 * 1024
+	// speedSampleInterval is how often the download speed is sampled
+	speedSampleInterval = time.Second
 )
 
 // WriteCounter tracks download progress.
 type WriteCounter struct {
 	TotalDownloaded uint64 // Total bytes downloaded
+	TotalSize       uint64 // Total size of the file in bytes, 0 if unknown
 	Percentage      string // Percentage completed (e.g., "1%")
-	Speed           string // Download speed (e.g., "35.4M")
+	Speed           string // Download speed (e.g., "35 MB")
 	RemainingTime   string // Time remaining (e.g., "18m41s")
+
+	mutex          sync.Mutex
+	sampleTime     time.Time
+	sampleBytes    uint64
+	bytesPerSecond float64
 }
 
 // NewWriteCounter creates a new WriteCounter.
 func NewWriteCounter() *WriteCounter {
 	return &WriteCounter{
 		TotalDownloaded: 0,
+		TotalSize:       0,
 		Percentage:      "0%",
-		Speed:           "0",
+		Speed:           "0 B",
 		RemainingTime:   "unknown",
 	}
 }
 
+// Write counts the bytes written through it, it is meant to be used with io.TeeReader or io.MultiWriter.
+func (wc *WriteCounter) Write(p []byte) (int, error) {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+
+	wc.TotalDownloaded += uint64(len(p))
+	wc.update()
+	metrics.DownloadedBytes.Add(float64(len(p)))
+	return len(p), nil
+}
+
+// start resets the counter for a download starting at offset bytes of size bytes.
+func (wc *WriteCounter) start(offset uint64, size uint64) {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+
+	wc.TotalDownloaded = offset
+	wc.TotalSize = size
+	wc.sampleTime = time.Now()
+	wc.sampleBytes = offset
+	wc.bytesPerSecond = 0
+	wc.update()
+}
+
+// update recalculates the derived fields, must be called with the mutex held.
+func (wc *WriteCounter) update() {
+	now := time.Now()
+	elapsed := now.Sub(wc.sampleTime)
+	if elapsed >= speedSampleInterval {
+		current := float64(wc.TotalDownloaded-wc.sampleBytes) / elapsed.Seconds()
+		if wc.bytesPerSecond == 0 {
+			wc.bytesPerSecond = current
+		} else {
+			// Smooth the speed so a single slow read doesn't make the ui jump around
+			wc.bytesPerSecond = 0.7*wc.bytesPerSecond + 0.3*current
+		}
+		wc.sampleTime = now
+		wc.sampleBytes = wc.TotalDownloaded
+	}
+
+	wc.Speed = humanize.Bytes(uint64(wc.bytesPerSecond))
+
+	if wc.TotalSize == 0 {
+		wc.Percentage = "0%"
+		wc.RemainingTime = "unknown"
+		return
+	}
+
+	wc.Percentage = fmt.Sprintf("%d%%", wc.TotalDownloaded*100/wc.TotalSize)
+	if wc.TotalDownloaded >= wc.TotalSize {
+		wc.RemainingTime = "0s"
+	} else if wc.bytesPerSecond > 0 {
+		remaining := float64(wc.TotalSize-wc.TotalDownloaded) / wc.bytesPerSecond
+		wc.RemainingTime = (time.Duration(remaining) * time.Second).String()
+	} else {
+		wc.RemainingTime = "unknown"
+	}
+}
+
 // GetSpeed returns the current download speed as a string.
 func (wc *WriteCounter) GetSpeed() string {
-	return fmt.Sprintf("%sB / Second", wc.Speed)
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+	return fmt.Sprintf("%s / Second", wc.Speed)
+}
+
+// GetBytesPerSecond returns the current download speed in bytes per second.
+func (wc *WriteCounter) GetBytesPerSecond() float64 {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+	return wc.bytesPerSecond
 }
 
 // GetProgress returns the progress as a human-readable string.
 func (wc *WriteCounter) GetProgress() string {
-	//log.Infof("GetProgress called: %s complete", humanize.Bytes(wc.TotalDownloaded))
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
 	return fmt.Sprintf("%s Complete (%s)", wc.Percentage, humanize.Bytes(wc.TotalDownloaded))
 }
 
-// DownloadFile uses wget for downloading and updates WriteCounter for progress tracking.
-func DownloadFile(ratelimit string, url string, filepath string, counter *WriteCounter) error {
-	// Prepare the wget command
-	cmd := exec.Command("stdbuf", "-oL", "wget", "-c", ratelimit, "--progress=dot:giga", "--no-use-server-timestamps", "-O", filepath, url)
-	// Get a pipe for the command's output
-	stdout, err := cmd.StderrPipe()
+// GetBytesDownloaded returns the number of bytes downloaded so far.
+func (wc *WriteCounter) GetBytesDownloaded() uint64 {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+	return wc.TotalDownloaded
+}
+
+// GetRemainingTime returns the estimated time until the download completes.
+func (wc *WriteCounter) GetRemainingTime() string {
+	wc.mutex.Lock()
+	defer wc.mutex.Unlock()
+	return wc.RemainingTime
+}
+
+// DownloadFile downloads url to filepath and updates WriteCounter for progress tracking.
+// An existing file at filepath is treated as a partial download and resumed using a HTTP Range request.
+// limiter may be nil for unlimited downloads.
+func DownloadFile(ctx context.Context, url string, filepath string, limiter *Limiter, counter *WriteCounter) error {
+	var offset int64
+	if fi, err := os.Stat(filepath); err == nil {
+		offset = fi.Size()
+	}
+
+	if offset > 0 {
+		log.Debugf("Resuming download of %s from byte %d", filepath, offset)
+	}
+	resp, err := requestFrom(ctx, url, offset)
 	if err != nil {
-		log.Errorf("failed to create stdout pipe: %w", err)
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		log.Errorf("failed to start wget: %w", err)
-		return fmt.Errorf("failed to start wget: %w", err)
-	}
-	// Regex to parse output lines
-	progressRegex := regexp.MustCompile(`(\d+)([KMG]?) .* (\d+%) (\d+\.\d+[KMG]) (\d+[smh][0-9]*[smh]?)`)
-	// Scan the output
-	scanner := bufio.NewScanner(stdout)
-	//log.Infof("Scanner created")
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Check if the line matches the regex
-		matches := progressRegex.FindStringSubmatch(line)
-		if len(matches) == 6 { // Full match expected
-			// Extract values
-			sizeStr, unit, percentage, speed, remainingTime := matches[1], matches[2], matches[3], matches[4], matches[5]
-			// Convert size to bytes
-			sizeInBytes, _ := strconv.ParseUint(sizeStr, 10, 64)
-			switch unit {
-			case "K":
-				sizeInBytes *= 1024
-			case "M":
-				sizeInBytes *= 1024 * 1024
-			case "G":
-				sizeInBytes *= 1024 * 1024 * 1024
-			}
+		return err
+	}
+	defer func() { resp.Body.Close() }()
 
-			// Update the counter with parsed values
-			counter.TotalDownloaded = sizeInBytes
-			counter.Percentage = percentage
-			counter.Speed = speed
-			counter.RemainingTime = remainingTime
+	if resp.StatusCode == http.StatusPartialContent {
+		if start := startFromContentRange(resp.Header.Get("Content-Range")); start != offset {
+			// Appending a different range would corrupt the file
+			log.Warnf("Server answered the resume of %s from byte %d with range %q, downloading it again", filepath, offset, resp.Header.Get("Content-Range"))
+			resp.Body.Close()
+			offset = 0
+			resp, err = requestFrom(ctx, url, offset)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-			// Print progress
-			//fmt.Printf("\r%s complete (%s, %s remaining)", counter.GetProgress(), counter.GetSpeed(), counter.RemainingTime)
-			//log.Infof("\r%s complete (%s, %s remaining)", counter.GetProgress(), counter.GetSpeed(), counter.RemainingTime)
+	flags := os.O_WRONLY | os.O_CREATE
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Either a fresh download or the server ignored the range header, start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusPartialContent:
+		if offset == 0 {
+			// Only a resumed download asks for a range
+			return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
 		}
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The range starts at or after the end of the file, the partial file is already complete
+		if size := totalSizeFromContentRange(resp.Header.Get("Content-Range")); size >= 0 && size == offset {
+			counter.start(uint64(offset), uint64(offset))
+			log.Debugf("File %s is already completely downloaded", filepath)
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+
+	var size uint64
+	if resp.ContentLength >= 0 {
+		size = uint64(offset + resp.ContentLength)
+	}
+	counter.start(uint64(offset), size)
+
+	file, err := os.OpenFile(filepath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	var body io.Reader = resp.Body
+	if limiter != nil {
+		body = &limitedReader{ctx: ctx, reader: body, limiter: limiter}
 	}
 
-	// Check for scanner errors
-	if err := scanner.Err(); err != nil {
-		log.Errorf("error reading wget output: %w", err)
-		return fmt.Errorf("error reading wget output: %w", err)
+	written, err := io.CopyBuffer(file, io.TeeReader(body, counter), make([]byte, copyBufferSize))
+	if err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
 	}
 
-	// Wait for wget to finish
-	if err := cmd.Wait(); err != nil {
-		log.Errorf("wget command failed: %i", err)
-		return fmt.Errorf("wget command failed: %i", err)
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return fmt.Errorf("%w: received %d of %d bytes", ErrIncompleteBody, written, resp.ContentLength)
 	}
 
-	fmt.Println("\nDownload completed successfully.")
+	log.Debugf("Download of %s completed successfully", filepath)
 	return nil
 }
+
+// requestFrom requests url starting at byte offset, the whole file if offset is 0
+func requestFrom(ctx context.Context, url string, offset int64) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request file: %w", err)
+	}
+	return resp, nil
+}
+
+// startFromContentRange parses the first byte from a Content-Range header (e.g. "bytes 100-1233/1234"), -1 if unknown
+func startFromContentRange(contentRange string) int64 {
+	rangeSpec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return -1
+	}
+	i := strings.Index(rangeSpec, "-")
+	if i < 0 {
+		return -1
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(rangeSpec[:i]), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
+// totalSizeFromContentRange parses the complete length from a Content-Range header (e.g. "bytes */1234"), -1 if unknown
+func totalSizeFromContentRange(contentRange string) int64 {
+	i := strings.LastIndex(contentRange, "/")
+	if i < 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
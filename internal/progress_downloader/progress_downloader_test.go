@@ -0,0 +1,108 @@
+package progress_downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var content = bytes.Repeat([]byte("0123456789"), 1000)
+
+// rangeServer serves content, answering range requests from the offset returned by start
+func rangeServer(t *testing.T, start func(requested int64) int64) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader := r.Header.Get("Range")
+		if rangeHeader == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content)
+			return
+		}
+		spec := strings.SplitN(strings.TrimPrefix(rangeHeader, "bytes="), "-", 2)
+		requested, err := strconv.ParseInt(spec[0], 10, 64)
+		if err != nil || len(spec) != 2 {
+			t.Errorf("unexpected range header %q", rangeHeader)
+			return
+		}
+		end := int64(len(content) - 1)
+		if spec[1] != "" {
+			end, err = strconv.ParseInt(spec[1], 10, 64)
+			if err != nil {
+				t.Errorf("unexpected range header %q", rangeHeader)
+				return
+			}
+		}
+		from := start(requested)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", from, end, len(content)))
+		w.Header().Set("Content-Length", strconv.FormatInt(end-from+1, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(content[from : end+1])
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func downloadPartial(t *testing.T, server *httptest.Server, partial int) []byte {
+	path := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(path, content[:partial], 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DownloadFile(context.Background(), server.URL, path, nil, NewWriteCounter())
+	if err != nil {
+		t.Fatalf("DownloadFile: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDownloadFileResumes(t *testing.T) {
+	server := rangeServer(t, func(requested int64) int64 { return requested })
+
+	if data := downloadPartial(t, server, 4321); !bytes.Equal(data, content) {
+		t.Errorf("resumed file differs from content, got %d bytes", len(data))
+	}
+}
+
+func TestDownloadFileRestartsOnMismatchedRange(t *testing.T) {
+	server := rangeServer(t, func(requested int64) int64 { return requested - 100 })
+
+	if data := downloadPartial(t, server, 4321); !bytes.Equal(data, content) {
+		t.Errorf("file differs from content after mismatched range, got %d bytes", len(data))
+	}
+}
+
+func TestDownloadFileRestartsWhenRangeIgnored(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	if data := downloadPartial(t, server, 4321); !bytes.Equal(data, content) {
+		t.Errorf("file differs from content after ignored range, got %d bytes", len(data))
+	}
+}
+
+func TestStartFromContentRange(t *testing.T) {
+	tests := map[string]int64{
+		"bytes 100-1233/1234": 100,
+		"bytes 0-9/*":         0,
+		"bytes */1234":        -1,
+		"":                    -1,
+	}
+	for header, want := range tests {
+		if got := startFromContentRange(header); got != want {
+			t.Errorf("startFromContentRange(%q) = %d, want %d", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package progress_downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRangesNotSupported = errors.New("server does not support range requests")
+)
+
+const (
+	// segmentStateSuffix is appended to the file path to store the progress of each segment
+	segmentStateSuffix = ".segments"
+	// segmentStateSaveInterval is how often the segment progress is written to disk
+	segmentStateSaveInterval = 5 * time.Second
+)
+
+// SegmentOptions configures splitting a download into byte ranges fetched in parallel.
+type SegmentOptions struct {
+	// Segments is the number of parallel connections per file, 1 or less disables segmenting
+	Segments int
+	// MinimumSize in bytes a file needs to have to be downloaded in segments
+	MinimumSize int64
+}
+
+type segment struct {
+	Start   int64 `json:"start"`
+	End     int64 `json:"end"` // inclusive
+	Written int64 `json:"written"`
+}
+
+func (s *segment) remaining() int64 {
+	return s.End - s.Start + 1 - s.Written
+}
+
+// segmentState is persisted next to the downloaded file so segments can be resumed after a crash.
+type segmentState struct {
+	mutex    sync.Mutex
+	path     string
+	Size     int64      `json:"size"`
+	Segments []*segment `json:"segments"`
+}
+
+func newSegmentState(path string, size int64, segments int) *segmentState {
+	state := &segmentState{path: path, Size: size}
+	segmentSize := size / int64(segments)
+	for i := 0; i < segments; i++ {
+		start := int64(i) * segmentSize
+		end := start + segmentSize - 1
+		if i == segments-1 {
+			end = size - 1
+		}
+		state.Segments = append(state.Segments, &segment{Start: start, End: end})
+	}
+	return state
+}
+
+func loadSegmentState(path string) (*segmentState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &segmentState{path: path}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *segmentState) save() error {
+	data, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+// write replaces the state file with data through a temporary file, a crash while writing never leaves a torn state behind
+func (s *segmentState) write(data []byte) error {
+	tmpPath := s.path + ".tmp"
+	err := os.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func (s *segmentState) snapshot() ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return json.Marshal(s)
+}
+
+// checkpoint saves the progress of the segments once the data written so far is flushed to file.
+// The progress is taken before the flush, the segments advance while it runs and the state must
+// never claim more than is on disk, otherwise a resume after a crash leaves zeros in the file
+func (s *segmentState) checkpoint(file *os.File) error {
+	data, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+	err = file.Sync()
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+func (s *segmentState) written() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var written int64
+	for _, seg := range s.Segments {
+		written += seg.Written
+	}
+	return written
+}
+
+func (s *segmentState) addWritten(seg *segment, n int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	seg.Written += n
+}
+
+// DownloadFileSegmented downloads url to filepath using options.Segments parallel range requests.
+// Files smaller than options.MinimumSize, servers without range support and partial files left by
+// DownloadFile fall back to a single stream DownloadFile.
+func DownloadFileSegmented(ctx context.Context, url string, filepath string, options SegmentOptions, limiter *Limiter, counter *WriteCounter) error {
+	if options.Segments <= 1 {
+		return DownloadFile(ctx, url, filepath, limiter, counter)
+	}
+
+	statePath := filepath + segmentStateSuffix
+	state, err := loadSegmentState(statePath)
+	if err == nil {
+		if _, statErr := os.Stat(filepath); statErr != nil {
+			// The segments describe data that is gone, allocating a new file would pass its zeros as downloaded
+			log.Debugf("Segment state of %s has no data file, starting over", filepath)
+			os.Remove(statePath)
+			err = statErr
+		}
+	} else if !os.IsNotExist(err) {
+		// The file was allocated to its full size, resuming it as a single stream would take its zeros as downloaded
+		log.Warnf("Segment state of %s cannot be read, starting over: %s", filepath, err)
+		os.Remove(statePath)
+		os.Remove(filepath)
+	}
+	if err != nil {
+		if _, statErr := os.Stat(filepath); statErr == nil {
+			log.Debugf("Found partial single stream download of %s, resuming without segments", filepath)
+			return DownloadFile(ctx, url, filepath, limiter, counter)
+		}
+
+		size, err := probeSize(ctx, url)
+		if err != nil {
+			log.Debugf("Cannot download %s in segments, falling back to single stream: %s", filepath, err)
+			return DownloadFile(ctx, url, filepath, limiter, counter)
+		}
+		if size < options.MinimumSize || size < int64(options.Segments) {
+			return DownloadFile(ctx, url, filepath, limiter, counter)
+		}
+		state = newSegmentState(statePath, size, options.Segments)
+	} else {
+		log.Debugf("Resuming segmented download of %s", filepath)
+	}
+
+	// The state has to exist before the file, otherwise a crash in between leaves a full sized
+	// file behind that looks like a completed single stream download
+	err = state.save()
+	if err != nil {
+		return fmt.Errorf("failed to save segment state: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	err = file.Truncate(state.Size)
+	if err != nil {
+		return fmt.Errorf("failed to allocate file: %w", err)
+	}
+
+	counter.start(uint64(state.written()), uint64(state.Size))
+
+	segmentCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(state.Segments))
+	for _, seg := range state.Segments {
+		if seg.remaining() <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(seg *segment) {
+			defer wg.Done()
+			err := downloadSegment(segmentCtx, url, file, state, seg, limiter, counter)
+			if err != nil {
+				errs <- err
+				// Stop the other segments, they are resumed together on the next attempt
+				cancel()
+			}
+		}(seg)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(segmentStateSaveInterval)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		case <-ticker.C:
+			state.checkpoint(file)
+		}
+	}
+	close(errs)
+
+	if err := <-errs; err != nil {
+		state.checkpoint(file)
+		return err
+	}
+	file.Sync()
+
+	err = os.Remove(statePath)
+	if err != nil {
+		log.Warnf("Failed to remove segment state %s: %s", statePath, err)
+	}
+
+	log.Debugf("Segmented download of %s completed successfully", filepath)
+	return nil
+}
+
+// probeSize requests the first byte of url to find out the file size and whether ranges are supported
+func probeSize(ctx context.Context, url string) (int64, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Range", "bytes=0-0")
+
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, ErrRangesNotSupported
+	}
+
+	size := totalSizeFromContentRange(resp.Header.Get("Content-Range"))
+	if size < 0 {
+		return 0, ErrRangesNotSupported
+	}
+	return size, nil
+}
+
+func downloadSegment(ctx context.Context, url string, file *os.File, state *segmentState, seg *segment, limiter *Limiter, counter *WriteCounter) error {
+	offset := seg.Start + seg.Written
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, seg.End))
+
+	resp, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to request segment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+	if start := startFromContentRange(resp.Header.Get("Content-Range")); start != offset {
+		// Writing a different range at offset would corrupt the file
+		return fmt.Errorf("%w: segment requested from byte %d was answered with range %q", ErrUnexpectedStatus, offset, resp.Header.Get("Content-Range"))
+	}
+
+	var body io.Reader = resp.Body
+	if limiter != nil {
+		body = &limitedReader{ctx: ctx, reader: body, limiter: limiter}
+	}
+
+	buffer := make([]byte, copyBufferSize)
+	for seg.remaining() > 0 {
+		n, readErr := body.Read(buffer)
+		if int64(n) > seg.remaining() {
+			n = int(seg.remaining())
+		}
+		if n > 0 {
+			_, err := file.WriteAt(buffer[:n], offset)
+			if err != nil {
+				return fmt.Errorf("failed to write segment: %w", err)
+			}
+			offset += int64(n)
+			state.addWritten(seg, int64(n))
+			counter.Write(buffer[:n])
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to download segment: %w", readErr)
+		}
+	}
+
+	if seg.remaining() > 0 {
+		return fmt.Errorf("%w: segment %d-%d is missing %d bytes", ErrIncompleteBody, seg.Start, seg.End, seg.remaining())
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package progress_downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var segmentOptions = SegmentOptions{Segments: 4, MinimumSize: 1}
+
+func TestDownloadFileSegmented(t *testing.T) {
+	server := rangeServer(t, func(requested int64) int64 { return requested })
+	path := filepath.Join(t.TempDir(), "file")
+
+	err := DownloadFileSegmented(context.Background(), server.URL, path, segmentOptions, nil, NewWriteCounter())
+	if err != nil {
+		t.Fatalf("DownloadFileSegmented: %s", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("downloaded file differs from content, got %d bytes", len(data))
+	}
+	if _, err := os.Stat(path + segmentStateSuffix); !os.IsNotExist(err) {
+		t.Errorf("segment state was not removed after the download: %v", err)
+	}
+}
+
+func TestDownloadFileSegmentedResetsStateWithoutFile(t *testing.T) {
+	server := rangeServer(t, func(requested int64) int64 { return requested })
+	path := filepath.Join(t.TempDir(), "file")
+
+	// A state claiming most of the file is downloaded, but the file itself is gone
+	state := newSegmentState(path+segmentStateSuffix, int64(len(content)), segmentOptions.Segments)
+	for _, seg := range state.Segments {
+		seg.Written = seg.End - seg.Start
+	}
+	err := state.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DownloadFileSegmented(context.Background(), server.URL, path, segmentOptions, nil, NewWriteCounter())
+	if err != nil {
+		t.Fatalf("DownloadFileSegmented: %s", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("downloaded file differs from content, the old segment state was trusted")
+	}
+}
+
+func TestDownloadFileSegmentedRestartsWithCorruptState(t *testing.T) {
+	server := rangeServer(t, func(requested int64) int64 { return requested })
+	path := filepath.Join(t.TempDir(), "file")
+
+	// A crash while the state was written leaves torn JSON next to the zero filled file
+	err := os.WriteFile(path, make([]byte, len(content)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path+segmentStateSuffix, []byte(`{"size":10000,"segments":[{"start":0,`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DownloadFileSegmented(context.Background(), server.URL, path, segmentOptions, nil, NewWriteCounter())
+	if err != nil {
+		t.Fatalf("DownloadFileSegmented: %s", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("downloaded file differs from content, the zero filled file was taken as complete")
+	}
+}
+
+func TestDownloadFileSegmentedRejectsMismatchedRange(t *testing.T) {
+	server := rangeServer(t, func(requested int64) int64 {
+		if requested > 0 {
+			return requested - 100
+		}
+		return requested
+	})
+	path := filepath.Join(t.TempDir(), "file")
+
+	err := DownloadFileSegmented(context.Background(), server.URL, path, segmentOptions, nil, NewWriteCounter())
+	if !errors.Is(err, ErrUnexpectedStatus) {
+		t.Fatalf("expected ErrUnexpectedStatus for a segment answered from the wrong offset, got %v", err)
+	}
+
+	state, err := loadSegmentState(path + segmentStateSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, seg := range state.Segments[1:] {
+		if seg.Written != 0 {
+			t.Errorf("segment %d-%d recorded %d bytes of the wrong range", seg.Start, seg.End, seg.Written)
+		}
+	}
+}
+
+func TestSegmentStateCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	file, err := os.Create(filepath.Join(dir, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	state := newSegmentState(filepath.Join(dir, "file"+segmentStateSuffix), 100, 2)
+	state.addWritten(state.Segments[0], 10)
+	err = state.checkpoint(file)
+	if err != nil {
+		t.Fatalf("checkpoint: %s", err)
+	}
+
+	loaded, err := loadSegmentState(state.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.written() != 10 {
+		t.Errorf("loaded state has %d bytes written, want 10", loaded.written())
+	}
+	if _, err := os.Stat(state.path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary state file was left behind: %v", err)
+	}
+}
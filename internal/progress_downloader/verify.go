@@ -0,0 +1,77 @@
+package progress_downloader
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	ErrSizeMismatch = errors.New("file size does not match")
+	ErrHashMismatch = errors.New("file hash does not match")
+)
+
+// openSubtitlesChunkSize is the size of the chunks at the start and end of the file the OpenSubtitles hash is calculated from
+const openSubtitlesChunkSize = 64 * 1024
+
+// VerifyFile compares the file at filepath to the expected size and OpenSubtitles hash.
+// A size of 0 or an empty hash skip the respective check.
+func VerifyFile(filepath string, size int64, openSubtitlesHash string) error {
+	fi, err := os.Stat(filepath)
+	if err != nil {
+		return err
+	}
+
+	if size > 0 && fi.Size() != size {
+		return fmt.Errorf("%w: expected %d bytes, found %d bytes", ErrSizeMismatch, size, fi.Size())
+	}
+
+	if openSubtitlesHash == "" || fi.Size() < openSubtitlesChunkSize {
+		return nil
+	}
+
+	hash, err := OpenSubtitlesHash(filepath)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(hash, openSubtitlesHash) {
+		return fmt.Errorf("%w: expected %s, calculated %s", ErrHashMismatch, openSubtitlesHash, hash)
+	}
+
+	return nil
+}
+
+// OpenSubtitlesHash calculates the hash used by opensubtitles.org, the file size plus the sum of
+// the first and last 64KiB read as little endian uint64s
+func OpenSubtitlesHash(filepath string) (string, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.Size() < openSubtitlesChunkSize {
+		return "", fmt.Errorf("file is smaller than %d bytes", openSubtitlesChunkSize)
+	}
+
+	hash := uint64(fi.Size())
+	chunk := make([]byte, openSubtitlesChunkSize)
+	for _, offset := range []int64{0, fi.Size() - openSubtitlesChunkSize} {
+		_, err = file.ReadAt(chunk, offset)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		for i := 0; i < openSubtitlesChunkSize; i += 8 {
+			hash += binary.LittleEndian.Uint64(chunk[i:])
+		}
+	}
+
+	return fmt.Sprintf("%016x", hash), nil
+}
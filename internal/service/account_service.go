@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/utils"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+// accountInfoInterval is how often the account info is requested
+const accountInfoInterval = 5 * time.Minute
+
+// AccountService polls the premium status and fair use of all accounts,
+// uploads to accounts above the FairUseUploadPauseThreshold are paused until they drop below it
+type AccountService struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       *sync.WaitGroup
+	accounts []*CloudAccount
+	config   *config.Config
+}
+
+func (AccountService) New() AccountService {
+	return AccountService{
+		ctx:      nil,
+		cancel:   nil,
+		wg:       &sync.WaitGroup{},
+		accounts: nil,
+		config:   nil,
+	}
+}
+
+func (s *AccountService) Init(ctx context.Context, accounts []*CloudAccount, config *config.Config) {
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.accounts = accounts
+	s.config = config
+}
+
+func (s *AccountService) Start() error {
+	log.Info("Starting account info poller...")
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			s.TaskUpdateAccountInfo()
+			if !utils.SleepContext(s.ctx, accountInfoInterval) {
+				log.Info("Account info poller stopped")
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the account info poller
+func (s *AccountService) Stop(ctx context.Context) error {
+	s.cancel()
+	if !utils.WaitContext(ctx, s.wg) {
+		return fmt.Errorf("account info poller did not stop in time: %w", ctx.Err())
+	}
+	return nil
+}
+
+func (s *AccountService) ConfigUpdatedCallback(currentConfig config.Config, newConfig config.Config) {
+	if currentConfig.FairUseUploadPauseThreshold != newConfig.FairUseUploadPauseThreshold {
+		log.Infof("Fair use upload pause threshold changed from %d%% to %d%%", currentConfig.FairUseUploadPauseThreshold, newConfig.FairUseUploadPauseThreshold)
+		for _, account := range s.accounts {
+			account.updateFairUseThreshold(newConfig.FairUseUploadPauseThreshold)
+		}
+	}
+}
+
+// TaskUpdateAccountInfo requests the account info of every account whose provider reports it
+func (s *AccountService) TaskUpdateAccountInfo() {
+	log.Debug("Running Task UpdateAccountInfo")
+	for _, account := range s.accounts {
+		provider, ok := account.Client.(clouddownloader.AccountInfoProvider)
+		if !ok {
+			continue
+		}
+
+		info, err := provider.GetAccountInfo(s.ctx)
+		if err != nil {
+			log.Errorf("Error getting account info of %s: %s", account.Name, err)
+			account.setAccountInfoError(err)
+			continue
+		}
+
+		log.Tracef("Account %s used %.1f%% of its fair use limit", account.Name, info.LimitUsed*100)
+		account.updateAccountInfo(info, s.config.FairUseUploadPauseThreshold)
+	}
+}
+
+// GetAccounts returns the status of all accounts
+func (s *AccountService) GetAccounts() []AccountStatus {
+	accounts := make([]AccountStatus, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		accounts = append(accounts, account.GetStatus())
+	}
+	return accounts
+}
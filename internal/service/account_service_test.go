@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+// accountInfoClient is a fakeClient reporting info as its account info
+type accountInfoClient struct {
+	fakeClient
+	info clouddownloader.AccountInfo
+	err  error
+}
+
+func (c *accountInfoClient) GetAccountInfo(ctx context.Context) (clouddownloader.AccountInfo, error) {
+	return c.info, c.err
+}
+
+// accountState is the state of a CloudAccount relevant for choosing where to upload
+type accountState struct {
+	name            string
+	activeTransfers int
+	limitUsed       float64
+	limitReached    bool
+	fairUseExceeded bool
+}
+
+func (s accountState) account() *CloudAccount {
+	account := NewCloudAccount(s.name, &fakeClient{})
+	for i := 0; i < s.activeTransfers; i++ {
+		account.transferAdded()
+	}
+	threshold := 0
+	if s.fairUseExceeded {
+		threshold = 1
+	}
+	account.updateAccountInfo(clouddownloader.AccountInfo{LimitUsed: s.limitUsed}, threshold)
+	if s.limitReached {
+		account.limitReached()
+	}
+	return account
+}
+
+func TestSelectUploadAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		accounts []accountState
+		want     string
+	}{
+		{"no accounts", nil, ""},
+		{"single account", []accountState{{name: "a"}}, "a"},
+		{"fewest active transfers", []accountState{{name: "a", activeTransfers: 3}, {name: "b", activeTransfers: 1}, {name: "c", activeTransfers: 2}}, "b"},
+		{"least fair use on a tie", []accountState{{name: "a", activeTransfers: 1, limitUsed: 0.5}, {name: "b", activeTransfers: 1, limitUsed: 0.2}}, "b"},
+		{"active transfers before fair use", []accountState{{name: "a", activeTransfers: 2, limitUsed: 0.1}, {name: "b", activeTransfers: 1, limitUsed: 0.8}}, "b"},
+		{"first account on a full tie", []accountState{{name: "a"}, {name: "b"}}, "a"},
+		{"skips limit reached", []accountState{{name: "a", limitReached: true}, {name: "b", activeTransfers: 5}}, "b"},
+		{"skips fair use exceeded", []accountState{{name: "a", limitUsed: 0.95, fairUseExceeded: true}, {name: "b", activeTransfers: 5}}, "b"},
+		{"all limit reached", []accountState{{name: "a", limitReached: true}, {name: "b", limitReached: true}}, ""},
+		{"all blocked", []accountState{{name: "a", limitReached: true}, {name: "b", limitUsed: 0.95, fairUseExceeded: true}}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accounts := make([]*CloudAccount, 0, len(test.accounts))
+			for _, state := range test.accounts {
+				accounts = append(accounts, state.account())
+			}
+
+			selected := selectUploadAccount(accounts)
+			if test.want == "" {
+				if selected != nil {
+					t.Errorf("expected no account, got %s", selected.Name)
+				}
+				return
+			}
+			if selected == nil || selected.Name != test.want {
+				t.Errorf("expected account %s, got %+v", test.want, selected)
+			}
+		})
+	}
+}
+
+func TestUploadsBlockedStatus(t *testing.T) {
+	tests := []struct {
+		accounts []accountState
+		want     string
+	}{
+		{nil, "No cloud downloader account configured!"},
+		{[]accountState{{name: "a", limitReached: true}}, "Limit of transfers reached!"},
+		{[]accountState{{name: "a", limitUsed: 0.95, fairUseExceeded: true}, {name: "b", limitReached: true}}, "Limit of transfers reached!"},
+		{[]accountState{{name: "a", limitUsed: 0.95, fairUseExceeded: true}}, "Uploads paused, fair use threshold reached!"},
+	}
+	for _, test := range tests {
+		accounts := make([]*CloudAccount, 0, len(test.accounts))
+		for _, state := range test.accounts {
+			accounts = append(accounts, state.account())
+		}
+		if got := uploadsBlockedStatus(accounts); got != test.want {
+			t.Errorf("uploadsBlockedStatus(%+v) = %q, want %q", test.accounts, got, test.want)
+		}
+	}
+}
+
+func TestFairUsePause(t *testing.T) {
+	client := &accountInfoClient{info: clouddownloader.AccountInfo{LimitUsed: 0.5}}
+	account := NewCloudAccount("Default", client)
+	cfg := &config.Config{FairUseUploadPauseThreshold: 90}
+	service := AccountService{}.New()
+	service.Init(context.Background(), []*CloudAccount{account}, cfg)
+
+	steps := []struct {
+		name      string
+		limitUsed float64
+		err       error
+		threshold int
+		paused    bool
+	}{
+		{"below threshold", 0.5, nil, 90, false},
+		{"at threshold", 0.9, nil, 90, true},
+		{"error keeps the pause", 0.1, errors.New("api down"), 90, true},
+		{"above threshold", 0.95, nil, 90, true},
+		{"threshold raised", 0.95, nil, 99, false},
+		{"threshold lowered", 0.95, nil, 80, true},
+		{"pausing disabled", 0.95, nil, 0, false},
+		{"threshold enabled again", 0.95, nil, 90, true},
+		{"dropped below threshold", 0.4, nil, 90, false},
+	}
+	for _, step := range steps {
+		client.info = clouddownloader.AccountInfo{LimitUsed: step.limitUsed}
+		client.err = step.err
+		if step.threshold != cfg.FairUseUploadPauseThreshold {
+			newConfig := *cfg
+			newConfig.FairUseUploadPauseThreshold = step.threshold
+			service.ConfigUpdatedCallback(*cfg, newConfig)
+			*cfg = newConfig
+		}
+		service.TaskUpdateAccountInfo()
+
+		status := service.GetAccounts()[0]
+		if status.UploadsPaused != step.paused {
+			t.Errorf("%s: uploads paused = %t, want %t", step.name, status.UploadsPaused, step.paused)
+		}
+		if selected := selectUploadAccount([]*CloudAccount{account}); (selected == nil) != step.paused {
+			t.Errorf("%s: selectUploadAccount returned %v", step.name, selected)
+		}
+		if step.err != nil && status.Error != step.err.Error() {
+			t.Errorf("%s: status error = %q, want %q", step.name, status.Error, step.err.Error())
+		}
+		if step.err == nil && status.Error != "" {
+			t.Errorf("%s: status error %q was not cleared", step.name, status.Error)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package service
 
 import (
+	"context"
 	"time"
 
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/arr"
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
-	log "github.com/sirupsen/logrus"
 	"golift.io/starr"
 	"golift.io/starr/radarr"
 	"golift.io/starr/sonarr"
@@ -25,7 +25,7 @@ func (am *ArrsManagerService) Init(_config *config.Config) {
 	am.config = _config
 }
 
-func (am *ArrsManagerService) Start() {
+func (am *ArrsManagerService) Start() error {
 	am.arrs = []arr.IArr{}
 	log.Debugf("Starting ArrsManagerService")
 	for _, arr_config := range am.config.Arrs {
@@ -53,14 +53,16 @@ func (am *ArrsManagerService) Start() {
 			am.arrs = append(am.arrs, &wrapper)
 			log.Tracef("Added Radarr arr: %s", arr_config.Name)
 		default:
-			log.Error("Unknown arr type: %s, not adding Arr %s", arr_config.Type, arr_config.Name)
+			log.Errorf("Unknown arr type: %s, not adding Arr %s", arr_config.Type, arr_config.Name)
 		}
 	}
 	log.Debugf("Created %d Arrs", len(am.arrs))
+	return nil
 }
 
-func (am *ArrsManagerService) Stop() {
-	//noop
+// Stop has nothing to stop, the arr histories are only requested by the other services
+func (am *ArrsManagerService) Stop(ctx context.Context) error {
+	return nil
 }
 
 func (am *ArrsManagerService) ConfigUpdatedCallback(currentConfig config.Config, newConfig config.Config) {
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/utils"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+// limitReachedBackoff is how long an account is skipped for uploads after it reported its transfer limit
+const limitReachedBackoff = time.Minute
+
+// CloudAccount is a cloud downloader account transfers are uploaded to and downloaded from
+type CloudAccount struct {
+	Name   string
+	Client clouddownloader.CloudDownloaderInterface
+
+	mutex             *sync.Mutex
+	downloadsFolderID string
+	activeTransfers   int
+	limitReachedUntil time.Time
+
+	accountInfo        *clouddownloader.AccountInfo
+	accountInfoUpdated time.Time
+	accountInfoError   string
+	fairUseExceeded    bool
+}
+
+// AccountStatus is a snapshot of the state of a CloudAccount for the web ui
+type AccountStatus struct {
+	Name            string  `json:"name"`
+	PremiumUntil    int64   `json:"premium_until"`
+	SpaceUsed       float64 `json:"space_used"`
+	LimitUsed       float64 `json:"limit_used"`
+	ActiveTransfers int     `json:"active_transfers"`
+	UploadsPaused   bool    `json:"uploads_paused"`
+	LastUpdated     int64   `json:"last_updated"`
+	Error           string  `json:"error"`
+}
+
+func NewCloudAccount(name string, client clouddownloader.CloudDownloaderInterface) *CloudAccount {
+	return &CloudAccount{
+		Name:   name,
+		Client: client,
+		mutex:  &sync.Mutex{},
+	}
+}
+
+// GetDownloadsFolderID returns the id of the folder transfers are saved to, it is looked up until it was found once.
+// The lookup runs without holding the mutex so the account stays usable while the API is slow
+func (a *CloudAccount) GetDownloadsFolderID(ctx context.Context) string {
+	a.mutex.Lock()
+	downloadsFolderID := a.downloadsFolderID
+	a.mutex.Unlock()
+	if downloadsFolderID != "" {
+		return downloadsFolderID
+	}
+
+	downloadsFolderID = utils.GetDownloadsFolderID(ctx, a.Client)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.downloadsFolderID == "" {
+		a.downloadsFolderID = downloadsFolderID
+	}
+	return a.downloadsFolderID
+}
+
+// GetActiveTransfers returns the number of transfers still running on the account as of the last poll
+func (a *CloudAccount) GetActiveTransfers() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.activeTransfers
+}
+
+// updateTransfers counts the transfers still running on the account
+func (a *CloudAccount) updateTransfers(transfers []clouddownloader.Transfer) {
+	active := 0
+	for _, transfer := range transfers {
+		if transfer.IsActive() {
+			active++
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.activeTransfers = active
+}
+
+// transferAdded counts an upload until the next poll of the transfers
+func (a *CloudAccount) transferAdded() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.activeTransfers++
+}
+
+// limitReached skips the account for uploads for limitReachedBackoff
+func (a *CloudAccount) limitReached() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.limitReachedUntil = time.Now().Add(limitReachedBackoff)
+}
+
+func (a *CloudAccount) isLimitReached() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return time.Now().Before(a.limitReachedUntil)
+}
+
+// updateAccountInfo stores info and pauses uploads when the used fair use limit reaches thresholdPercent, 0 disables pausing
+func (a *CloudAccount) updateAccountInfo(info clouddownloader.AccountInfo, thresholdPercent int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.accountInfo = &info
+	a.accountInfoUpdated = time.Now()
+	a.accountInfoError = ""
+	a.applyFairUseThreshold(thresholdPercent)
+}
+
+// updateFairUseThreshold reevaluates the last account info against thresholdPercent
+func (a *CloudAccount) updateFairUseThreshold(thresholdPercent int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.applyFairUseThreshold(thresholdPercent)
+}
+
+// applyFairUseThreshold must be called with the mutex held
+func (a *CloudAccount) applyFairUseThreshold(thresholdPercent int) {
+	exceeded := a.accountInfo != nil && thresholdPercent > 0 && a.accountInfo.LimitUsed*100 >= float64(thresholdPercent)
+	if exceeded && !a.fairUseExceeded {
+		log.Warnf("Account %s used %.0f%% of its fair use limit, pausing uploads", a.Name, a.accountInfo.LimitUsed*100)
+	} else if !exceeded && a.fairUseExceeded {
+		log.Infof("Account %s is below the fair use threshold again, resuming uploads", a.Name)
+	}
+	a.fairUseExceeded = exceeded
+}
+
+func (a *CloudAccount) setAccountInfoError(err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.accountInfoError = err.Error()
+}
+
+func (a *CloudAccount) isFairUseExceeded() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.fairUseExceeded
+}
+
+// limitUsed returns the used fraction of the fair use limit, 0 if unknown
+func (a *CloudAccount) limitUsed() float64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.accountInfo == nil {
+		return 0
+	}
+	return a.accountInfo.LimitUsed
+}
+
+// GetStatus returns a snapshot of the account
+func (a *CloudAccount) GetStatus() AccountStatus {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	status := AccountStatus{
+		Name:            a.Name,
+		ActiveTransfers: a.activeTransfers,
+		UploadsPaused:   a.fairUseExceeded || time.Now().Before(a.limitReachedUntil),
+		Error:           a.accountInfoError,
+	}
+	if a.accountInfo != nil {
+		status.PremiumUntil = a.accountInfo.PremiumUntil
+		status.SpaceUsed = a.accountInfo.SpaceUsed
+		status.LimitUsed = a.accountInfo.LimitUsed
+		status.LastUpdated = a.accountInfoUpdated.Unix()
+	}
+	return status
+}
+
+// selectUploadAccount returns the account with the fewest active transfers, then the least used fair use limit,
+// that has neither reached its transfer limit nor the fair use threshold, nil if none is left
+func selectUploadAccount(accounts []*CloudAccount) *CloudAccount {
+	var selected *CloudAccount
+	for _, account := range accounts {
+		if account.isLimitReached() || account.isFairUseExceeded() {
+			continue
+		}
+		if selected == nil ||
+			account.GetActiveTransfers() < selected.GetActiveTransfers() ||
+			(account.GetActiveTransfers() == selected.GetActiveTransfers() && account.limitUsed() < selected.limitUsed()) {
+			selected = account
+		}
+	}
+	return selected
+}
+
+// uploadsBlockedStatus describes why selectUploadAccount found no account
+func uploadsBlockedStatus(accounts []*CloudAccount) string {
+	if len(accounts) == 0 {
+		return "No cloud downloader account configured!"
+	}
+	for _, account := range accounts {
+		if !account.isFairUseExceeded() {
+			return "Limit of transfers reached!"
+		}
+	}
+	return "Uploads paused, fair use threshold reached!"
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+// directDownloadIDPrefix marks journal entries of direct downloads, they have no folder on the cloud downloader
+const directDownloadIDPrefix = "directdl:"
+
+// directDownload is a cached release downloaded straight from the cloud downloader without creating a transfer
+type directDownload struct {
+	id      string
+	name    string
+	account *CloudAccount
+	files   []clouddownloader.DirectDownloadFile
+	// sourcePath is the blackhole file of the release, it is removed once the download completed
+	sourcePath string
+	// requeue hands sourcePath back to the blackhole queue when the download failed
+	requeue func(sourcePath string)
+	started bool
+}
+
+func isDirectDownloadID(id string) bool {
+	return strings.HasPrefix(id, directDownloadIDPrefix)
+}
+
+// AddDirectDownload queues the files of the cached release name with the infohash hash for downloading,
+// requeue is called with sourcePath if the download fails so the release is uploaded instead
+func (manager *TransferManagerService) AddDirectDownload(account *CloudAccount, hash string, name string, files []clouddownloader.DirectDownloadFile, sourcePath string, requeue func(sourcePath string)) {
+	id := directDownloadIDPrefix + hash
+
+	manager.directDownloadsMutex.Lock()
+	defer manager.directDownloadsMutex.Unlock()
+
+	if _, ok := manager.directDownloads[id]; ok {
+		log.Tracef("Direct download %s is already queued", name)
+		return
+	}
+
+	manager.directDownloads[id] = &directDownload{
+		id:         id,
+		name:       name,
+		account:    account,
+		files:      files,
+		sourcePath: sourcePath,
+		requeue:    requeue,
+	}
+	log.Infof("Queued direct download of %s with %d files", name, len(files))
+}
+
+// IsDirectDownload reports whether the blackhole file sourcePath is queued or downloading as a direct download
+func (manager *TransferManagerService) IsDirectDownload(sourcePath string) bool {
+	manager.directDownloadsMutex.Lock()
+	defer manager.directDownloadsMutex.Unlock()
+
+	for _, download := range manager.directDownloads {
+		if download.sourcePath == sourcePath {
+			return true
+		}
+	}
+	return false
+}
+
+// DirectDownloadFailed reports whether the direct download of the blackhole file sourcePath failed,
+// the failure is forgotten once reported so the release is uploaded instead only once
+func (manager *TransferManagerService) DirectDownloadFailed(sourcePath string) bool {
+	manager.directDownloadsMutex.Lock()
+	defer manager.directDownloadsMutex.Unlock()
+
+	failed := manager.failedDirectDownloads[sourcePath]
+	delete(manager.failedDirectDownloads, sourcePath)
+	return failed
+}
+
+// TaskStartDirectDownloads starts queued direct downloads as long as fewer than SimultaneousDownloads are running
+func (manager *TransferManagerService) TaskStartDirectDownloads() {
+	log.Debug("Running Task StartDirectDownloads")
+
+	if manager.limiter.IsPaused() {
+		log.Debug("Downloads are paused by the bandwidth schedule, not starting new downloads")
+		return
+	}
+
+	manager.directDownloadsMutex.Lock()
+	queued := make([]*directDownload, 0)
+	for _, download := range manager.directDownloads {
+		if !download.started {
+			queued = append(queued, download)
+		}
+	}
+	manager.directDownloadsMutex.Unlock()
+
+	for _, download := range queued {
+		if manager.countDownloads() >= manager.config.SimultaneousDownloads {
+			log.Debugf("Not starting any more direct downloads, %d are running and cap is %d", manager.countDownloads(), manager.config.SimultaneousDownloads)
+			return
+		}
+		manager.startDirectDownload(download)
+	}
+}
+
+func (manager *TransferManagerService) startDirectDownload(download *directDownload) {
+	downloadDirectory := manager.config.DownloadsDirectory
+	stagingDirectory := path.Join(downloadDirectory, partialDirectoryName)
+	err := os.MkdirAll(stagingDirectory, os.ModePerm)
+	if err != nil {
+		log.Errorf("Error creating staging directory %s: %s", stagingDirectory, err)
+		return
+	}
+
+	manager.directDownloadsMutex.Lock()
+	download.started = true
+	manager.directDownloadsMutex.Unlock()
+
+	root := clouddownloader.Item{ID: download.id, Name: download.name, Type: clouddownloader.ItemTypeFolder}
+	manager.addDownload(download.account.Name, &root)
+	manager.journal.AddDirectFolder(download.account.Name, download.id, download.name, path.Join(stagingDirectory, download.name), download.sourcePath)
+	log.Infof("Starting direct download of %s", download.name)
+
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		defer manager.removeDirectDownload(download.id)
+		defer manager.removeDownload(download.account.Name, download.id)
+
+		for _, file := range download.files {
+			// Clean the path as an absolute one so it cannot point outside of the download
+			filePath := path.Clean("/" + file.Path)
+			fileSavePath := path.Join(stagingDirectory, download.name, filePath)
+			err := os.MkdirAll(path.Dir(fileSavePath), os.ModePerm)
+			if err != nil {
+				manager.failDirectDownload(download, stagingDirectory, fmt.Errorf("error creating directory for %s: %w", fileSavePath, err))
+				return
+			}
+
+			item := clouddownloader.Item{
+				ID:   download.id + filePath,
+				Name: path.Base(filePath),
+				Type: clouddownloader.ItemTypeFile,
+				Link: file.Link,
+				Size: file.Size,
+			}
+			err = manager.downloadVerifiedFile(download.account.Name, download.id, item, item, fileSavePath)
+			if err != nil && manager.ctx.Err() != nil {
+				log.Infof("Direct download of %s interrupted, it is resumed once its blackhole file is processed again", download.name)
+				return
+			}
+			if err != nil {
+				manager.failDirectDownload(download, stagingDirectory, err)
+				return
+			}
+		}
+
+		err := manager.completeDownload(download.name, stagingDirectory, downloadDirectory)
+		if err != nil {
+			manager.failDirectDownload(download, stagingDirectory, err)
+			return
+		}
+
+		err = os.Remove(download.sourcePath)
+		if err != nil {
+			log.Errorf("Error could not delete %s Error: %+v", download.sourcePath, err)
+		}
+
+		manager.journal.RemoveFolder(download.account.Name, download.id)
+	}()
+}
+
+// failDirectDownload removes the journal entry and the partial files of the failed direct download
+// and hands its blackhole file back to the queue, where it is uploaded as a transfer instead
+func (manager *TransferManagerService) failDirectDownload(download *directDownload, stagingDirectory string, err error) {
+	log.Errorf("Error downloading direct download %s, uploading it instead: %s", download.name, err)
+	manager.publishDownloadError(download.name, download.account.Name, err)
+
+	manager.journal.RemoveFolder(download.account.Name, download.id)
+	err = os.RemoveAll(path.Join(stagingDirectory, download.name))
+	if err != nil {
+		log.Errorf("Error removing partial direct download %s: %s", download.name, err)
+	}
+
+	// Forget the download before requeueing, otherwise the queue skips the file as queued for direct download
+	manager.removeDirectDownload(download.id)
+	manager.directDownloadsMutex.Lock()
+	manager.failedDirectDownloads[download.sourcePath] = true
+	manager.directDownloadsMutex.Unlock()
+	if download.requeue != nil {
+		download.requeue(download.sourcePath)
+	}
+}
+
+// removeDirectDownload forgets a finished or failed direct download
+func (manager *TransferManagerService) removeDirectDownload(id string) {
+	manager.directDownloadsMutex.Lock()
+	defer manager.directDownloadsMutex.Unlock()
+	delete(manager.directDownloads, id)
+}
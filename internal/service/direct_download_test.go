@@ -0,0 +1,104 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+const testMagnet = "magnet:?xt=urn:btih:22441b101748ffbc862312d8d3f5c355e740dd13"
+
+func TestFailedDirectDownloadIsUploadedInstead(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	client := &fakeClient{cached: true, directFiles: []clouddownloader.DirectDownloadFile{{Path: "Release/release.mkv", Size: 10, Link: server.URL + "/release.mkv"}}}
+	account := NewCloudAccount("Default", client)
+	cfg := newTestConfig(t)
+	cfg.DirectDownloadCached = true
+	manager, watcher := newTestServices(t, cfg, account)
+
+	sourcePath := path.Join(cfg.BlackholeDirectory, "release.magnet")
+	err := os.WriteFile(sourcePath, []byte(testMagnet), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cached release is handed to the transfer manager instead of being uploaded
+	watcher.processUpload(sourcePath)
+	if !manager.IsDirectDownload(sourcePath) {
+		t.Fatal("cached release was not queued for direct download")
+	}
+	if created := client.getCreated(); len(created) != 0 {
+		t.Fatalf("transfer created for a direct download: %v", created)
+	}
+
+	manager.TaskStartDirectDownloads()
+	manager.wg.Wait()
+
+	// The failed download leaves nothing behind and its blackhole file is queued again
+	if folders := manager.journal.GetFolders(); len(folders) != 0 {
+		t.Errorf("failed direct download is still journaled: %+v", folders)
+	}
+	if _, err := os.Stat(path.Join(cfg.DownloadsDirectory, partialDirectoryName, "release")); !os.IsNotExist(err) {
+		t.Errorf("partial files of the failed direct download were kept: %v", err)
+	}
+	if manager.IsDirectDownload(sourcePath) {
+		t.Error("failed direct download is still queued")
+	}
+	if manager.countDownloads() != 0 {
+		t.Errorf("failed direct download is still listed as running")
+	}
+	ok, requeued := watcher.Queue.PopTopOfQueue()
+	if !ok || requeued != sourcePath {
+		t.Fatalf("blackhole file was not queued again, got %q", requeued)
+	}
+
+	// The second attempt uploads the release as a transfer
+	watcher.processUpload(requeued)
+	if created := client.getCreated(); len(created) != 1 || created[0] != "release.magnet" {
+		t.Errorf("expected a transfer of release.magnet, got %v", created)
+	}
+	if manager.IsDirectDownload(sourcePath) {
+		t.Error("release was queued for direct download again")
+	}
+	if _, err := os.Stat(sourcePath); !os.IsNotExist(err) {
+		t.Errorf("uploaded blackhole file was kept: %v", err)
+	}
+}
+
+func TestResumeDropsDirectDownloadWithoutBlackholeFile(t *testing.T) {
+	account := NewCloudAccount("Default", &fakeClient{})
+	cfg := newTestConfig(t)
+	manager, _ := newTestServices(t, cfg, account)
+	stagingDirectory := path.Join(cfg.DownloadsDirectory, partialDirectoryName)
+
+	keptSource := path.Join(cfg.BlackholeDirectory, "kept.magnet")
+	err := os.WriteFile(keptSource, []byte(testMagnet), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"kept", "gone"} {
+		err = os.MkdirAll(path.Join(stagingDirectory, name), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manager.journal.AddDirectFolder(account.Name, directDownloadIDPrefix+name, name, path.Join(stagingDirectory, name), path.Join(cfg.BlackholeDirectory, name+".magnet"))
+	}
+
+	manager.ResumeJournaledDownloads()
+
+	folders := manager.journal.GetFolders()
+	if len(folders) != 1 || folders[0].Name != "kept" {
+		t.Errorf("expected only the direct download with a blackhole file to be kept, got %+v", folders)
+	}
+	if _, err := os.Stat(path.Join(stagingDirectory, "gone")); !os.IsNotExist(err) {
+		t.Errorf("partial files of the dropped direct download were kept: %v", err)
+	}
+	if _, err := os.Stat(path.Join(stagingDirectory, "kept")); err != nil {
+		t.Errorf("partial files of the kept direct download were removed: %v", err)
+	}
+}
@@ -1,200 +1,490 @@
-package service
-
-import (
-	"io/ioutil"
-	"os"
-	"path"
-	"path/filepath"
-	"time"
-
-	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
-	"github.com/ensingerphilipp/premiumizearr-nova/internal/directory_watcher"
-	"github.com/ensingerphilipp/premiumizearr-nova/internal/utils"
-	"github.com/ensingerphilipp/premiumizearr-nova/pkg/premiumizeme"
-	"github.com/ensingerphilipp/premiumizearr-nova/pkg/stringqueue"
-	log "github.com/sirupsen/logrus"
-)
-
-type DirectoryWatcherService struct {
-	premiumizemeClient *premiumizeme.Premiumizeme
-	config             *config.Config
-	Queue              *stringqueue.StringQueue
-	status             string
-	downloadsFolderID  string
-	watchDirectory     *directory_watcher.WatchDirectory
-}
-
-const (
-	ERROR_LIMIT_REACHED    = "Limit of transfers reached!"
-	ERROR_ALREADY_UPLOADED = "You already added this job."
-)
-
-func (DirectoryWatcherService) New() DirectoryWatcherService {
-	return DirectoryWatcherService{
-		premiumizemeClient: nil,
-		config:             nil,
-		Queue:              nil,
-		status:             "",
-		downloadsFolderID:  "",
-	}
-}
-
-func (dw *DirectoryWatcherService) Init(premiumizemeClient *premiumizeme.Premiumizeme, config *config.Config) {
-	dw.premiumizemeClient = premiumizemeClient
-	dw.config = config
-}
-
-func (dw *DirectoryWatcherService) ConfigUpdatedCallback(currentConfig config.Config, newConfig config.Config) {
-	if currentConfig.BlackholeDirectory != newConfig.BlackholeDirectory {
-		log.Info("Blackhole directory changed, restarting directory watcher...")
-		log.Info("Running initial directory scan...")
-		go dw.directoryScan(dw.config.BlackholeDirectory)
-		dw.watchDirectory.UpdatePath(newConfig.BlackholeDirectory)
-	}
-
-	if currentConfig.PollBlackholeDirectory != newConfig.PollBlackholeDirectory {
-		log.Info("Poll blackhole directory changed, restarting directory watcher...")
-		dw.Start()
-	}
-}
-
-func (dw *DirectoryWatcherService) GetStatus() string {
-	return dw.status
-}
-
-// Start: This is the entrypoint for the directory watcher
-func (dw *DirectoryWatcherService) Start() {
-	log.Info("Starting directory watcher...")
-
-	dw.downloadsFolderID = utils.GetDownloadsFolderIDFromPremiumizeme(dw.premiumizemeClient)
-
-	log.Info("Creating Queue...")
-	dw.Queue = stringqueue.NewStringQueue()
-
-	log.Info("Starting uploads processor...")
-	go dw.processUploads()
-
-	log.Info("Running initial directory scan...")
-	go dw.directoryScan(dw.config.BlackholeDirectory)
-
-	if dw.watchDirectory != nil {
-		log.Info("Stopping directory watcher...")
-		err := dw.watchDirectory.Stop()
-		if err != nil {
-			log.Errorf("Error stopping directory watcher: %s", err)
-		}
-	}
-
-	if dw.config.PollBlackholeDirectory {
-		log.Info("Starting directory poller...")
-		go func() {
-			for {
-				if !dw.config.PollBlackholeDirectory {
-					log.Info("Directory poller stopped")
-					break
-				}
-				time.Sleep(time.Duration(dw.config.PollBlackholeIntervalMinutes) * time.Minute)
-				log.Infof("Running directory scan of %s", dw.config.BlackholeDirectory)
-				dw.directoryScan(dw.config.BlackholeDirectory)
-				log.Infof("Scan complete, next scan in %d minutes", dw.config.PollBlackholeIntervalMinutes)
-			}
-		}()
-	} else {
-		log.Info("Starting directory watcher...")
-		dw.watchDirectory = directory_watcher.NewDirectoryWatcher(dw.config.BlackholeDirectory,
-			true,
-			dw.checkFile,
-			dw.addFileToQueue,
-		)
-		dw.watchDirectory.Watch()
-	}
-}
-
-func (dw *DirectoryWatcherService) directoryScan(p string) {
-	log.Trace("Running directory scan")
-	files, err := ioutil.ReadDir(p)
-	if err != nil {
-		log.Errorf("Error with directory scan %+v", err)
-		return
-	}
-
-	for _, file := range files {
-		go func(file os.FileInfo) {
-			file_path := path.Join(p, file.Name())
-			if dw.checkFile(file_path) == 1 {
-				dw.addFileToQueue(file_path)
-			}
-		}(file)
-	}
-}
-
-func (dw *DirectoryWatcherService) checkFile(path string) int {
-	log.Tracef("Checking file %s", path)
-
-	fi, err := os.Stat(path)
-	if err != nil {
-		log.Errorf("Error checking file %s", path)
-		return 0
-	}
-
-	if fi.IsDir() {
-		log.Errorf("Directory created in blackhole %s ignoring (Warning premiumizearrd does not look in subfolders!)", path)
-		return 2
-	}
-
-	ext := filepath.Ext(path)
-	if ext == ".nzb" || ext == ".magnet" || ext == ".torrent" {
-		return 1
-	} else {
-		return 0
-	}
-}
-
-func (dw *DirectoryWatcherService) addFileToQueue(path string) {
-	dw.Queue.Add(path)
-	log.Infof("File created in blackhole %s added to Queue. Queue length %d", path, dw.Queue.Len())
-}
-
-func (dw *DirectoryWatcherService) processUploads() {
-	for {
-		if dw.Queue.Len() < 1 {
-			log.Trace("No files in Queue, sleeping for 10 seconds")
-			time.Sleep(time.Second * time.Duration(10))
-		}
-
-		isQueueFile, filePath := dw.Queue.PopTopOfQueue()
-		if !isQueueFile {
-			time.Sleep(time.Second * time.Duration(10))
-			continue
-		}
-
-		sleepTimeSeconds := 2
-		if filePath != "" {
-			log.Debugf("Processing %s", filePath)
-			err := dw.premiumizemeClient.CreateTransfer(filePath, dw.downloadsFolderID)
-			if err != nil {
-				switch err.Error() {
-				case ERROR_LIMIT_REACHED:
-					dw.status = "Limit of transfers reached!"
-					log.Trace("Transfer limit reached waiting 10 seconds and retrying")
-					sleepTimeSeconds = 10
-				case ERROR_ALREADY_UPLOADED:
-					log.Trace("File already uploaded, removing from Disk")
-					os.Remove(filePath)
-				default:
-					log.Error("Error creating transfer: %s", err)
-				}
-			} else {
-				dw.status = "Okay"
-				os.Remove(filePath)
-				if err != nil {
-					log.Errorf("Error could not delete %s Error: %+v", filePath, err)
-				}
-				log.Infof("Removed %s from blackhole Queue. Queue Size: %d", filePath, dw.Queue.Len())
-			}
-			time.Sleep(time.Second * time.Duration(sleepTimeSeconds))
-		} else {
-			log.Errorf("Received %s from blackhole Queue. Appears to be an empty path.")
-		}
-	}
-}
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/directory_watcher"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/events"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/metrics"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/utils"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/infohash"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/stringqueue"
+	"github.com/sirupsen/logrus"
+)
+
+type DirectoryWatcherService struct {
+	ctx             context.Context
+	cancel          context.CancelFunc
+	wg              *sync.WaitGroup
+	accounts        []*CloudAccount
+	arrsManager     *ArrsManagerService
+	transferManager *TransferManagerService
+	events          *events.Bus
+	config          *config.Config
+	Queue           *stringqueue.StringQueue
+	status          string
+	// watchMutex guards watchDirectory and stopPoller, which are replaced when the blackhole is watched differently
+	watchMutex     *sync.Mutex
+	watchDirectory *directory_watcher.WatchDirectory
+	stopPoller     context.CancelFunc
+	// uncached holds the rejected releases which were not found in an arr history yet and when they were rejected
+	uncached      map[string]time.Time
+	uncachedMutex *sync.Mutex
+}
+
+// uncachedRejectTimeout is how long a rejected release is looked for in the arr histories before it is dropped
+const uncachedRejectTimeout = 2 * time.Minute
+
+func (DirectoryWatcherService) New() DirectoryWatcherService {
+	return DirectoryWatcherService{
+		ctx:             nil,
+		cancel:          nil,
+		wg:              &sync.WaitGroup{},
+		accounts:        nil,
+		arrsManager:     nil,
+		transferManager: nil,
+		events:          nil,
+		config:          nil,
+		Queue:           nil,
+		status:          "",
+		watchMutex:      &sync.Mutex{},
+		uncached:        make(map[string]time.Time),
+		uncachedMutex:   &sync.Mutex{},
+	}
+}
+
+func (dw *DirectoryWatcherService) Init(ctx context.Context, accounts []*CloudAccount, arrsManager *ArrsManagerService, transferManager *TransferManagerService, bus *events.Bus, config *config.Config) {
+	dw.ctx, dw.cancel = context.WithCancel(ctx)
+	dw.accounts = accounts
+	dw.arrsManager = arrsManager
+	dw.transferManager = transferManager
+	dw.events = bus
+	dw.config = config
+}
+
+// ConfigUpdatedCallback watches the blackhole as configured, the upload queue and its processor keep running
+func (dw *DirectoryWatcherService) ConfigUpdatedCallback(currentConfig config.Config, newConfig config.Config) {
+	if currentConfig.BlackholeDirectory != newConfig.BlackholeDirectory {
+		log.Info("Blackhole directory changed, running initial directory scan...")
+		dw.wg.Add(1)
+		go func() {
+			defer dw.wg.Done()
+			dw.directoryScan(newConfig.BlackholeDirectory)
+		}()
+	}
+
+	if currentConfig.PollBlackholeDirectory != newConfig.PollBlackholeDirectory {
+		log.Info("Poll blackhole directory changed, restarting directory watcher...")
+		dw.startWatching()
+	} else if currentConfig.BlackholeDirectory != newConfig.BlackholeDirectory {
+		dw.watchMutex.Lock()
+		defer dw.watchMutex.Unlock()
+		if dw.watchDirectory != nil {
+			err := dw.watchDirectory.UpdatePath(newConfig.BlackholeDirectory)
+			if err != nil {
+				log.Errorf("Error watching blackhole directory %s: %s", newConfig.BlackholeDirectory, err)
+			}
+		}
+	}
+}
+
+func (dw *DirectoryWatcherService) GetStatus() string {
+	return dw.status
+}
+
+// Start: This is the entrypoint for the directory watcher
+func (dw *DirectoryWatcherService) Start() error {
+	log.Info("Starting directory watcher...")
+
+	log.Info("Creating Queue...")
+	dw.Queue = stringqueue.NewStringQueue()
+
+	log.Info("Starting uploads processor...")
+	dw.wg.Add(1)
+	go func() {
+		defer dw.wg.Done()
+		dw.processUploads()
+	}()
+
+	log.Info("Running initial directory scan...")
+	dw.wg.Add(2)
+	go func() {
+		defer dw.wg.Done()
+		dw.directoryScan(dw.config.BlackholeDirectory)
+	}()
+	go func() {
+		defer dw.wg.Done()
+		dw.scanSubmissions()
+	}()
+
+	dw.startWatching()
+	return nil
+}
+
+// startWatching stops watching or polling the blackhole and starts again as configured
+func (dw *DirectoryWatcherService) startWatching() {
+	dw.watchMutex.Lock()
+	defer dw.watchMutex.Unlock()
+
+	if dw.watchDirectory != nil {
+		log.Info("Stopping directory watcher...")
+		err := dw.watchDirectory.Stop()
+		if err != nil {
+			log.Errorf("Error stopping directory watcher: %s", err)
+		}
+		dw.watchDirectory = nil
+	}
+	if dw.stopPoller != nil {
+		dw.stopPoller()
+		dw.stopPoller = nil
+	}
+
+	if dw.config.PollBlackholeDirectory {
+		log.Info("Starting directory poller...")
+		var pollerCtx context.Context
+		pollerCtx, dw.stopPoller = context.WithCancel(dw.ctx)
+		dw.wg.Add(1)
+		go func() {
+			defer dw.wg.Done()
+			for {
+				if !utils.SleepContext(pollerCtx, time.Duration(dw.config.PollBlackholeIntervalMinutes)*time.Minute) {
+					log.Info("Directory poller stopped")
+					break
+				}
+				log.Infof("Running directory scan of %s", dw.config.BlackholeDirectory)
+				dw.directoryScan(dw.config.BlackholeDirectory)
+				log.Infof("Scan complete, next scan in %d minutes", dw.config.PollBlackholeIntervalMinutes)
+			}
+		}()
+	} else {
+		log.Info("Starting directory watcher...")
+		dw.watchDirectory = directory_watcher.NewDirectoryWatcher(dw.config.BlackholeDirectory,
+			true,
+			dw.checkFile,
+			dw.addFileToQueue,
+		)
+		err := dw.watchDirectory.Watch()
+		if err != nil {
+			log.Errorf("Error watching blackhole directory %s: %s", dw.config.BlackholeDirectory, err)
+		}
+	}
+}
+
+// Stop stops accepting blackhole files and waits for the upload in progress, which is canceled, to return
+func (dw *DirectoryWatcherService) Stop(ctx context.Context) error {
+	log.Info("Stopping directory watcher...")
+	dw.cancel()
+	dw.watchMutex.Lock()
+	if dw.watchDirectory != nil {
+		err := dw.watchDirectory.Stop()
+		if err != nil {
+			log.Errorf("Error stopping directory watcher: %s", err)
+		}
+	}
+	dw.watchMutex.Unlock()
+
+	if !utils.WaitContext(ctx, dw.wg) {
+		return fmt.Errorf("uploads processor did not stop in time: %w", ctx.Err())
+	}
+	log.Info("Directory watcher stopped")
+	return nil
+}
+
+func (dw *DirectoryWatcherService) directoryScan(p string) {
+	log.Trace("Running directory scan")
+	files, err := ioutil.ReadDir(p)
+	if err != nil {
+		log.Errorf("Error with directory scan %+v", err)
+		return
+	}
+
+	for _, file := range files {
+		dw.wg.Add(1)
+		go func(file os.FileInfo) {
+			defer dw.wg.Done()
+			file_path := path.Join(p, file.Name())
+			if dw.checkFile(file_path) == 1 {
+				dw.addFileToQueue(file_path)
+			}
+		}(file)
+	}
+}
+
+func (dw *DirectoryWatcherService) checkFile(path string) int {
+	log.Tracef("Checking file %s", path)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		log.Errorf("Error checking file %s", path)
+		return 0
+	}
+
+	if fi.IsDir() {
+		log.Errorf("Directory created in blackhole %s ignoring (Warning premiumizearrd does not look in subfolders!)", path)
+		return 2
+	}
+
+	ext := filepath.Ext(path)
+	if ext == ".nzb" || ext == ".magnet" || ext == ".torrent" {
+		return 1
+	} else {
+		return 0
+	}
+}
+
+func (dw *DirectoryWatcherService) addFileToQueue(path string) {
+	dw.Queue.Add(path)
+	metrics.BlackholeFilesQueued.Inc()
+	log.Infof("File created in blackhole %s added to Queue. Queue length %d", path, dw.Queue.Len())
+	dw.publishQueued(path)
+}
+
+func (dw *DirectoryWatcherService) processUploads() {
+	for {
+		if dw.ctx.Err() != nil {
+			log.Info("Uploads processor stopped")
+			return
+		}
+
+		if dw.Queue.Len() < 1 {
+			log.Trace("No files in Queue, sleeping for 10 seconds")
+			utils.SleepContext(dw.ctx, time.Second*time.Duration(10))
+		}
+
+		isQueueFile, filePath := dw.Queue.PopTopOfQueue()
+		if !isQueueFile {
+			utils.SleepContext(dw.ctx, time.Second*time.Duration(10))
+			continue
+		}
+
+		if filePath != "" {
+			utils.SleepContext(dw.ctx, dw.processUpload(filePath))
+		} else {
+			log.Error("Received an empty path from blackhole Queue")
+		}
+	}
+}
+
+// processUpload uploads the blackhole file filePath or hands it to the transfer manager for a direct download,
+// returns how long to wait before the next file
+func (dw *DirectoryWatcherService) processUpload(filePath string) time.Duration {
+	sleepTimeSeconds := 2
+	account := selectUploadAccount(dw.accounts)
+	if account == nil {
+		dw.status = uploadsBlockedStatus(dw.accounts)
+		log.Trace("No account can take the transfer, waiting 10 seconds and retrying")
+		dw.Queue.Add(filePath)
+		return time.Second * time.Duration(10)
+	}
+
+	if dw.isRejected(filePath) {
+		dw.rejectUncached(filePath)
+		return time.Second * time.Duration(sleepTimeSeconds)
+	}
+
+	if dw.transferManager.IsDirectDownload(filePath) {
+		log.Tracef("%s is already queued for direct download", filePath)
+		return 0
+	}
+
+	hash, cached := dw.checkCache(account, filePath)
+	if cached && dw.config.DirectDownloadCached && !dw.transferManager.DirectDownloadFailed(filePath) && dw.directDownload(account, hash, filePath) {
+		dw.status = "Okay"
+		dw.assignCategory(filePath)
+		metrics.BlackholeFilesUploaded.Inc()
+		dw.publishUploaded(account, filePath)
+		return 0
+	}
+	if hash != "" && !cached && dw.config.CacheCheckMode == config.CacheCheckRejectUncached {
+		dw.rejectUncached(filePath)
+		return time.Second * time.Duration(sleepTimeSeconds)
+	}
+
+	log.WithFields(logrus.Fields{"file": filePath, "account": account.Name}).Debug("Processing blackhole file")
+	err := dw.createTransfer(account, filePath)
+	if err != nil {
+		switch {
+		case errors.Is(err, clouddownloader.ErrLimitReached):
+			log.WithField("account", account.Name).Info("Transfer limit reached, trying the other accounts")
+			account.limitReached()
+			dw.Queue.Add(filePath)
+			sleepTimeSeconds = 0
+		case errors.Is(err, clouddownloader.ErrDuplicate):
+			log.Trace("File already uploaded, removing from Disk")
+			os.Remove(filePath)
+		default:
+			log.WithFields(logrus.Fields{"file": filePath, "account": account.Name}).WithError(err).Error("Error creating transfer")
+			metrics.BlackholeFilesFailed.WithLabelValues("error").Inc()
+			dw.events.Publish(events.Failed, events.Failure{
+				Source:  "upload",
+				Name:    filepath.Base(filePath),
+				Account: account.Name,
+				Message: err.Error(),
+			})
+			dw.removeFailedSubmission(filePath)
+		}
+	} else {
+		dw.status = "Okay"
+		account.transferAdded()
+		dw.assignCategory(filePath)
+		metrics.BlackholeFilesUploaded.Inc()
+		dw.publishUploaded(account, filePath)
+		err = os.Remove(filePath)
+		if err != nil {
+			log.Errorf("Error could not delete %s Error: %+v", filePath, err)
+		}
+		log.Infof("Removed %s from blackhole Queue. Queue Size: %d", filePath, dw.Queue.Len())
+	}
+	return time.Second * time.Duration(sleepTimeSeconds)
+}
+
+// checkCache returns the infohash of the release at filePath and whether it is cached on account.
+// The hash is empty if the cache was not checked, which is the case for .nzb files and cloud downloaders without a cache.
+func (dw *DirectoryWatcherService) checkCache(account *CloudAccount, filePath string) (string, bool) {
+	if dw.config.CacheCheckMode == config.CacheCheckDisabled && !dw.config.DirectDownloadCached {
+		return "", false
+	}
+
+	checker, ok := account.Client.(clouddownloader.CacheChecker)
+	if !ok {
+		log.WithFields(logrus.Fields{"file": filePath, "account": account.Name}).Debug("Cloud downloader has no cache check, uploading file")
+		return "", false
+	}
+
+	ext := filepath.Ext(filePath)
+	if ext != ".torrent" && ext != ".magnet" {
+		return "", false
+	}
+
+	hash, err := infohash.FromFile(filePath)
+	if err != nil {
+		log.Errorf("Error reading infohash of %s, uploading it without cache check: %s", filePath, err)
+		return "", false
+	}
+
+	cached, err := checker.CheckCache(dw.ctx, []string{hash})
+	if err != nil {
+		log.Errorf("Error checking cache for %s, uploading it anyway: %s", filePath, err)
+		return "", false
+	}
+
+	if cached[0] {
+		log.WithFields(logrus.Fields{"file": filepath.Base(filePath), "hash": hash, "account": account.Name}).Info("Release is cached")
+	} else {
+		log.WithFields(logrus.Fields{"file": filepath.Base(filePath), "hash": hash, "account": account.Name}).Info("Release is not cached")
+	}
+	return hash, cached[0]
+}
+
+// directDownload hands the cached release at filePath to the transfer manager without creating a transfer,
+// returns false if the release has to be uploaded instead
+func (dw *DirectoryWatcherService) directDownload(account *CloudAccount, hash string, filePath string) bool {
+	downloader, ok := account.Client.(clouddownloader.DirectDownloader)
+	if !ok {
+		return false
+	}
+
+	files, err := downloader.DirectDownload(dw.ctx, "magnet:?xt=urn:btih:"+hash)
+	if err != nil {
+		log.Errorf("Error requesting direct download of %s, uploading it instead: %s", filePath, err)
+		return false
+	}
+	if len(files) == 0 {
+		log.Warnf("Direct download of %s returned no files, uploading it instead", filePath)
+		return false
+	}
+
+	name := utils.StripDownloadTypesExtention(filepath.Base(filePath))
+	dw.transferManager.AddDirectDownload(account, hash, name, files, filePath, dw.addFileToQueue)
+	return true
+}
+
+// isRejected reports whether the release at filePath was rejected as uncached and is waiting to be found in an arr history
+func (dw *DirectoryWatcherService) isRejected(filePath string) bool {
+	dw.uncachedMutex.Lock()
+	defer dw.uncachedMutex.Unlock()
+	_, ok := dw.uncached[filePath]
+	return ok
+}
+
+// rejectUncached marks the release at filePath as failed in the arr that grabbed it and removes it from the blackhole.
+// The release is queued again if no arr history contains it yet, until uncachedRejectTimeout passed.
+func (dw *DirectoryWatcherService) rejectUncached(filePath string) {
+	name := filepath.Base(filePath)
+	dw.uncachedMutex.Lock()
+	rejectedAt, ok := dw.uncached[filePath]
+	if !ok {
+		rejectedAt = time.Now()
+		dw.uncached[filePath] = rejectedAt
+	}
+	dw.uncachedMutex.Unlock()
+
+	found := false
+	for _, arr := range dw.arrsManager.GetArrs() {
+		arrID, contains := arr.HistoryContains(name)
+		if !contains {
+			continue
+		}
+		found = true
+		log.WithFields(logrus.Fields{"release": name, "arr": arr.GetArrName()}).Info("Marking uncached release as failed")
+		err := arr.MarkHistoryItemAsFailed(arrID)
+		if err != nil {
+			log.WithFields(logrus.Fields{"release": name, "arr": arr.GetArrName()}).WithError(err).Error("Error marking release as failed")
+		}
+		break
+	}
+
+	if !found && time.Since(rejectedAt) < uncachedRejectTimeout {
+		log.Debugf("Uncached release %s not found in any arr history yet, retrying", name)
+		dw.Queue.Add(filePath)
+		return
+	}
+
+	if !found {
+		log.Warnf("Uncached release %s not found in any arr history, removing it without marking it as failed", name)
+	}
+
+	dw.uncachedMutex.Lock()
+	delete(dw.uncached, filePath)
+	dw.uncachedMutex.Unlock()
+	err := os.Remove(filePath)
+	if err != nil {
+		log.Errorf("Error could not delete %s Error: %+v", filePath, err)
+	}
+	dw.status = "Rejected uncached release " + name
+	metrics.BlackholeFilesFailed.WithLabelValues("uncached").Inc()
+	dw.events.Publish(events.Failed, events.Failure{
+		Source:  "upload",
+		Name:    name,
+		Message: "release is not cached",
+	})
+}
+
+// publishQueued reports the blackhole file or submission at filePath added to the upload queue to the event subscribers
+func (dw *DirectoryWatcherService) publishQueued(filePath string) {
+	dw.events.Publish(events.BlackholeFileQueued, events.BlackholeFile{
+		Name:        filepath.Base(filePath),
+		Category:    dw.submissionCategory(filePath),
+		QueueLength: dw.Queue.Len(),
+	})
+}
+
+// publishUploaded reports the blackhole file or submission at filePath uploaded to account to the event subscribers
+func (dw *DirectoryWatcherService) publishUploaded(account *CloudAccount, filePath string) {
+	dw.events.Publish(events.BlackholeFileUploaded, events.BlackholeFile{
+		Name:        filepath.Base(filePath),
+		Category:    dw.submissionCategory(filePath),
+		Account:     account.Name,
+		QueueLength: dw.Queue.Len(),
+	})
+}
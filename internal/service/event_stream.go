@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// eventStreamKeepAlive is the interval comments are sent at while no events are, so proxies keep idle streams open
+const eventStreamKeepAlive = 15 * time.Second
+
+// eventStream writes Server-Sent Events to a response
+type eventStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// newEventStream starts the event stream response, it lifts the write timeout of the server for the response
+func newEventStream(w http.ResponseWriter) (*eventStream, error) {
+	controller := http.NewResponseController(w)
+	err := controller.SetWriteDeadline(time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("error lifting write deadline of event stream: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Keep nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	return &eventStream{w: w, controller: controller}, controller.Flush()
+}
+
+// Send writes data as json encoded event, id is omitted when 0
+func (s *eventStream) Send(id uint64, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if id != 0 {
+		_, err = fmt.Fprintf(s.w, "id: %d\n", id)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	if err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// KeepAlive writes a comment, which clients ignore
+func (s *eventStream) KeepAlive() error {
+	_, err := fmt.Fprint(s.w, ": keep-alive\n\n")
+	if err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// lastEventID returns the id of the last event a reconnecting client received, 0 if it did not send one
+func lastEventID(r *http.Request) uint64 {
+	id, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
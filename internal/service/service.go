@@ -1,12 +1,24 @@
 package service
 
 import (
-	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"context"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/logging"
 )
 
-//Service interface
+var log = logging.Subsystem("service")
+
+// Service is a long running part of the daemon. Stop returns once everything the service started has stopped,
+// or with an error once ctx is done.
 type Service interface {
-	New() (*config.Config, error)
 	Start() error
-	Stop() error
+	Stop(ctx context.Context) error
 }
+
+var (
+	_ Service = (*ArrsManagerService)(nil)
+	_ Service = (*AccountService)(nil)
+	_ Service = (*DirectoryWatcherService)(nil)
+	_ Service = (*TransferManagerService)(nil)
+	_ Service = (*WebServerService)(nil)
+)
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/download_journal"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/events"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/progress_downloader"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/stringqueue"
+)
+
+// fakeClient is a cloud downloader with an empty downloads folder, it records the transfers created on it
+type fakeClient struct {
+	mutex sync.Mutex
+	// cached is the answer to every cache check
+	cached bool
+	// directFiles are returned by DirectDownload
+	directFiles []clouddownloader.DirectDownloadFile
+	// createErr is returned when a transfer is created
+	createErr error
+	// created holds the file names, magnets and links transfers were created of
+	created []string
+}
+
+func (c *fakeClient) record(src string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.createErr != nil {
+		return c.createErr
+	}
+	c.created = append(c.created, src)
+	return nil
+}
+
+func (c *fakeClient) getCreated() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]string(nil), c.created...)
+}
+
+func (c *fakeClient) GetTransfers(ctx context.Context) ([]clouddownloader.Transfer, error) {
+	return nil, nil
+}
+
+func (c *fakeClient) CreateTransfer(ctx context.Context, filePath string, parentID string) error {
+	return c.record(filepath.Base(filePath))
+}
+
+func (c *fakeClient) DeleteTransfer(ctx context.Context, id string) error {
+	return nil
+}
+
+func (c *fakeClient) GetFolders(ctx context.Context) ([]clouddownloader.Item, error) {
+	return []clouddownloader.Item{{ID: "downloads", Name: "arrDownloads", Type: clouddownloader.ItemTypeFolder}}, nil
+}
+
+func (c *fakeClient) ListFolder(ctx context.Context, folderID string) ([]clouddownloader.Item, error) {
+	return nil, nil
+}
+
+func (c *fakeClient) CreateFolder(ctx context.Context, folderName string, parentID *string) (string, error) {
+	return folderName, nil
+}
+
+func (c *fakeClient) DeleteFolder(ctx context.Context, folderID string) error {
+	return nil
+}
+
+func (c *fakeClient) MoveItem(ctx context.Context, itemID string, folderID string) error {
+	return nil
+}
+
+func (c *fakeClient) GenerateFileLink(ctx context.Context, ID string) (string, error) {
+	return "", nil
+}
+
+func (c *fakeClient) GetItemDetails(ctx context.Context, ID string) (clouddownloader.Item, error) {
+	return clouddownloader.Item{}, nil
+}
+
+func (c *fakeClient) CheckCache(ctx context.Context, items []string) ([]bool, error) {
+	cached := make([]bool, len(items))
+	for i := range cached {
+		cached[i] = c.cached
+	}
+	return cached, nil
+}
+
+func (c *fakeClient) DirectDownload(ctx context.Context, src string) ([]clouddownloader.DirectDownloadFile, error) {
+	return c.directFiles, nil
+}
+
+func (c *fakeClient) CreateMagnetTransfer(ctx context.Context, magnet string, parentID string) (clouddownloader.CreatedTransfer, error) {
+	return clouddownloader.CreatedTransfer{ID: "magnet", Name: magnet}, c.record(magnet)
+}
+
+func (c *fakeClient) CreateLinkTransfer(ctx context.Context, link string, parentID string) (clouddownloader.CreatedTransfer, error) {
+	return clouddownloader.CreatedTransfer{ID: "link", Name: link}, c.record(link)
+}
+
+func (c *fakeClient) CreateFileTransfer(ctx context.Context, fileName string, data []byte, parentID string) (clouddownloader.CreatedTransfer, error) {
+	return clouddownloader.CreatedTransfer{ID: "file", Name: fileName}, c.record(fileName)
+}
+
+// newTestConfig returns the default config stored in a temporary config directory, downloads go to a temporary directory
+func newTestConfig(t *testing.T) *config.Config {
+	cfg, err := config.LoadOrCreateConfig(t.TempDir(), func(oldConfig config.Config, newConfig config.Config) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DownloadsDirectory = t.TempDir()
+	cfg.BlackholeDirectory = t.TempDir()
+	return &cfg
+}
+
+// newTestServices returns a transfer manager and a directory watcher sharing cfg and accounts without starting them,
+// both are stopped when the test ends
+func newTestServices(t *testing.T, cfg *config.Config, accounts ...*CloudAccount) (*TransferManagerService, *DirectoryWatcherService) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bus := events.NewBus()
+
+	manager := TransferManagerService{}.New()
+	manager.ctx, manager.cancel = ctx, cancel
+	manager.accounts = accounts
+	manager.events = bus
+	manager.config = cfg
+	manager.limiter = progress_downloader.NewLimiter(0)
+	journal, err := download_journal.LoadJournal(path.Join(cfg.GetConfigDirectory(), journalFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.journal = journal
+	manager.loadCategories()
+
+	watcher := DirectoryWatcherService{}.New()
+	watcher.Init(ctx, accounts, nil, &manager, bus, cfg)
+	watcher.Queue = stringqueue.NewStringQueue()
+
+	t.Cleanup(func() {
+		cancel()
+		watcher.cancel()
+		manager.wg.Wait()
+	})
+	return &manager, &watcher
+}
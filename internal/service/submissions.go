@@ -0,0 +1,246 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/metrics"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/utils"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/infohash"
+)
+
+const (
+	// submissionsDirectoryName is the directory next to the config transfers submitted through the api are saved in until
+	// they are uploaded, submissions with a category are saved in a subdirectory named after the category
+	submissionsDirectoryName = "submissions"
+	// linkExtension marks submitted links, they are uploaded through clouddownloader.TransferSubmitter
+	linkExtension = ".link"
+	// MaxSubmissionSize limits the size of submitted .torrent, .nzb and .magnet files
+	MaxSubmissionSize = 10 << 20
+)
+
+var (
+	ErrInvalidCategory   = errors.New("category may only contain letters, digits, spaces, dots, dashes and underscores and must not start with a dot")
+	ErrInvalidSubmission = errors.New("only .torrent, .nzb and .magnet files can be submitted")
+	ErrInvalidLink       = errors.New("link must be an http or https url")
+	ErrLinksNotSupported = errors.New("no configured cloud downloader accepts links")
+	ErrSubmissionQueued  = errors.New("a submission with this name is already queued")
+	ErrNotStarted        = errors.New("directory watcher is not started")
+)
+
+var categoryPattern = regexp.MustCompile(`^[A-Za-z0-9_\- ][A-Za-z0-9_\-. ]*$`)
+
+// unsafeFileNameCharacters are replaced in the names of submissions so they are valid file names on every platform
+var unsafeFileNameCharacters = regexp.MustCompile(`[/\\:*?"<>|\x00-\x1f]`)
+
+func (dw *DirectoryWatcherService) submissionsDirectory() string {
+	return path.Join(dw.config.GetConfigDirectory(), submissionsDirectoryName)
+}
+
+// SubmitMagnet queues magnet for upload and returns the name of the release,
+// which is the display name of the magnet or its infohash if it has none
+func (dw *DirectoryWatcherService) SubmitMagnet(magnet string, category string) (string, error) {
+	magnet = strings.TrimSpace(magnet)
+	hash, err := infohash.FromMagnet(magnet)
+	if err != nil {
+		return "", err
+	}
+
+	name := hash
+	u, err := url.Parse(magnet)
+	if err == nil && u.Query().Get("dn") != "" {
+		name = u.Query().Get("dn")
+	}
+	return dw.submit(name+".magnet", []byte(magnet), category)
+}
+
+// SubmitLink queues the http or https link for upload and returns the name of the release, the last element of the url path
+func (dw *DirectoryWatcherService) SubmitLink(link string, category string) (string, error) {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidLink
+	}
+	if !dw.acceptsLinks() {
+		return "", ErrLinksNotSupported
+	}
+
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		name = u.Host
+	}
+	return dw.submit(name+linkExtension, []byte(link), category)
+}
+
+// SubmitFile queues the .torrent, .nzb or .magnet file fileName with the content data for upload and returns the name of the release
+func (dw *DirectoryWatcherService) SubmitFile(fileName string, data []byte, category string) (string, error) {
+	fileName = filepath.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	ext := filepath.Ext(fileName)
+	if ext != ".torrent" && ext != ".nzb" && ext != ".magnet" {
+		return "", ErrInvalidSubmission
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("%w: %s is empty", ErrInvalidSubmission, fileName)
+	}
+	return dw.submit(fileName, data, category)
+}
+
+// submit saves data as fileName in the submissions directory of category and adds it to the upload queue
+func (dw *DirectoryWatcherService) submit(fileName string, data []byte, category string) (string, error) {
+	if dw.Queue == nil {
+		return "", ErrNotStarted
+	}
+	if category != "" && !categoryPattern.MatchString(category) {
+		return "", ErrInvalidCategory
+	}
+
+	fileName = strings.TrimSpace(unsafeFileNameCharacters.ReplaceAllString(fileName, "_"))
+	name := strings.TrimSuffix(utils.StripDownloadTypesExtention(fileName), linkExtension)
+	if name == "" || strings.HasPrefix(fileName, ".") {
+		return "", fmt.Errorf("%w: %s is no valid release name", ErrInvalidSubmission, fileName)
+	}
+
+	directory := path.Join(dw.submissionsDirectory(), category)
+	err := os.MkdirAll(directory, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("error creating submissions directory %s: %w", directory, err)
+	}
+
+	filePath := path.Join(directory, fileName)
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return "", ErrSubmissionQueued
+	}
+	if err != nil {
+		return "", fmt.Errorf("error saving submission %s: %w", fileName, err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return "", fmt.Errorf("error saving submission %s: %w", fileName, err)
+	}
+
+	dw.Queue.Add(filePath)
+	metrics.BlackholeFilesQueued.Inc()
+	if category != "" {
+		log.Infof("Submitted %s with category %s added to Queue. Queue length %d", name, category, dw.Queue.Len())
+	} else {
+		log.Infof("Submitted %s added to Queue. Queue length %d", name, dw.Queue.Len())
+	}
+	dw.publishQueued(filePath)
+	return name, nil
+}
+
+// scanSubmissions queues the submissions left over from the last run
+func (dw *DirectoryWatcherService) scanSubmissions() {
+	directory := dw.submissionsDirectory()
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("Error with submissions scan %+v", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			dw.queueSubmission(path.Join(directory, entry.Name()))
+			continue
+		}
+
+		categoryDirectory := path.Join(directory, entry.Name())
+		files, err := os.ReadDir(categoryDirectory)
+		if err != nil {
+			log.Errorf("Error with submissions scan %+v", err)
+			continue
+		}
+		for _, file := range files {
+			if !file.IsDir() {
+				dw.queueSubmission(path.Join(categoryDirectory, file.Name()))
+			}
+		}
+	}
+}
+
+func (dw *DirectoryWatcherService) queueSubmission(filePath string) {
+	ext := filepath.Ext(filePath)
+	if ext != ".torrent" && ext != ".nzb" && ext != ".magnet" && ext != linkExtension {
+		return
+	}
+	dw.Queue.Add(filePath)
+	metrics.BlackholeFilesQueued.Inc()
+	log.Infof("Submission %s added to Queue. Queue length %d", filePath, dw.Queue.Len())
+	dw.publishQueued(filePath)
+}
+
+// submissionCategory returns the category of the release at filePath, which is empty for blackhole files and submissions without category
+func (dw *DirectoryWatcherService) submissionCategory(filePath string) string {
+	directory := path.Dir(filePath)
+	if path.Dir(directory) != path.Clean(dw.submissionsDirectory()) {
+		return ""
+	}
+	return path.Base(directory)
+}
+
+// removeFailedSubmission deletes the submission at filePath after its upload failed, so it can be submitted again.
+// Blackhole files are left in place as before
+func (dw *DirectoryWatcherService) removeFailedSubmission(filePath string) {
+	if !strings.HasPrefix(filePath, path.Clean(dw.submissionsDirectory())+"/") {
+		return
+	}
+	err := os.Remove(filePath)
+	if err != nil {
+		log.Errorf("Error could not delete failed submission %s Error: %+v", filePath, err)
+		return
+	}
+	log.Infof("Removed failed submission %s, it can be submitted again", filePath)
+}
+
+// acceptsLinks reports whether any account can upload links
+func (dw *DirectoryWatcherService) acceptsLinks() bool {
+	for _, account := range dw.accounts {
+		if _, ok := account.Client.(clouddownloader.TransferSubmitter); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// createTransfer uploads the blackhole file or submission at filePath to account
+func (dw *DirectoryWatcherService) createTransfer(account *CloudAccount, filePath string) error {
+	if filepath.Ext(filePath) != linkExtension {
+		return account.Client.CreateTransfer(dw.ctx, filePath, account.GetDownloadsFolderID(dw.ctx))
+	}
+
+	submitter, ok := account.Client.(clouddownloader.TransferSubmitter)
+	if !ok {
+		return fmt.Errorf("cloud downloader of account %s does not accept links", account.Name)
+	}
+	link, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	_, err = submitter.CreateLinkTransfer(dw.ctx, strings.TrimSpace(string(link)), account.GetDownloadsFolderID(dw.ctx))
+	return err
+}
+
+// assignCategory tells the transfer manager to complete the release at filePath into the directory of its category
+func (dw *DirectoryWatcherService) assignCategory(filePath string) {
+	category := dw.submissionCategory(filePath)
+	if category == "" {
+		return
+	}
+	name := strings.TrimSuffix(utils.StripDownloadTypesExtention(filepath.Base(filePath)), linkExtension)
+	dw.transferManager.SetCategory(name, category)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestCategoryPattern(t *testing.T) {
+	tests := map[string]bool{
+		"tv":          true,
+		"TV Shows":    true,
+		"movies-4k":   true,
+		"anime_2024":  true,
+		"v1.2":        true,
+		".hidden":     false,
+		"..":          false,
+		"a/b":         false,
+		"../movies":   false,
+		`a\b`:         false,
+		"tv:shows":    false,
+		"new\nline":   false,
+		"émissions":   false,
+		"movies*":     false,
+		"-dash-first": true,
+	}
+	for category, valid := range tests {
+		if got := categoryPattern.MatchString(category); got != valid {
+			t.Errorf("categoryPattern.MatchString(%q) = %t, want %t", category, got, valid)
+		}
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	cfg := newTestConfig(t)
+	_, watcher := newTestServices(t, cfg, NewCloudAccount("Default", &fakeClient{}))
+
+	tests := []struct {
+		fileName string
+		category string
+		wantName string
+		wantPath string
+		wantErr  error
+	}{
+		{"Some.Release.magnet", "", "Some.Release", "Some.Release.magnet", nil},
+		{"Some.Show.S01E01.torrent", "TV Shows", "Some.Show.S01E01", "TV Shows/Some.Show.S01E01.torrent", nil},
+		{"example.org.link", "movies", "example.org", "movies/example.org.link", nil},
+		{"Un/safe:Name?.nzb", "", "Un_safe_Name_", "Un_safe_Name_.nzb", nil},
+		{"Some.Release.magnet", "", "", "", ErrSubmissionQueued},
+		{"Other.Release.magnet", "../escape", "", "", ErrInvalidCategory},
+		{"Other.Release.magnet", ".hidden", "", "", ErrInvalidCategory},
+		{".magnet", "", "", "", ErrInvalidSubmission},
+		{".hidden.torrent", "", "", "", ErrInvalidSubmission},
+	}
+	queued := 0
+	for _, test := range tests {
+		name, err := watcher.submit(test.fileName, []byte("data"), test.category)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("submit(%q, %q): expected error %v, got %v", test.fileName, test.category, test.wantErr, err)
+			continue
+		}
+		if name != test.wantName {
+			t.Errorf("submit(%q, %q) = %q, want %q", test.fileName, test.category, name, test.wantName)
+		}
+		if test.wantErr != nil {
+			continue
+		}
+		queued++
+
+		filePath := path.Join(watcher.submissionsDirectory(), test.wantPath)
+		data, err := os.ReadFile(filePath)
+		if err != nil || string(data) != "data" {
+			t.Errorf("submission %q was not saved to %s: %v", test.fileName, filePath, err)
+		}
+		if category := watcher.submissionCategory(filePath); category != test.category {
+			t.Errorf("submissionCategory(%s) = %q, want %q", filePath, category, test.category)
+		}
+	}
+	if watcher.Queue.Len() != queued {
+		t.Errorf("expected %d queued submissions, got %d", queued, watcher.Queue.Len())
+	}
+
+	entries, err := os.ReadDir(path.Dir(watcher.submissionsDirectory()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == "escape" {
+			t.Error("submission was saved outside of the submissions directory")
+		}
+	}
+
+	watcher.Queue = nil
+	_, err = watcher.submit("Late.Release.magnet", []byte("data"), "")
+	if !errors.Is(err, ErrNotStarted) {
+		t.Errorf("expected ErrNotStarted without a queue, got %v", err)
+	}
+}
+
+func TestSubmissionCategory(t *testing.T) {
+	cfg := newTestConfig(t)
+	_, watcher := newTestServices(t, cfg)
+	submissions := watcher.submissionsDirectory()
+
+	tests := map[string]string{
+		path.Join(submissions, "tv", "Release.magnet"):        "tv",
+		path.Join(submissions, "Release.magnet"):              "",
+		path.Join(submissions, "tv", "nested", "Rel.magnet"):  "",
+		path.Join(cfg.BlackholeDirectory, "Release.magnet"):   "",
+		path.Join(cfg.BlackholeDirectory, "tv", "Rel.magnet"): "",
+	}
+	for filePath, want := range tests {
+		if got := watcher.submissionCategory(filePath); got != want {
+			t.Errorf("submissionCategory(%s) = %q, want %q", filePath, got, want)
+		}
+	}
+}
+
+func TestRemoveFailedSubmission(t *testing.T) {
+	cfg := newTestConfig(t)
+	client := &fakeClient{createErr: errors.New("upload failed")}
+	_, watcher := newTestServices(t, cfg, NewCloudAccount("Default", client))
+
+	_, err := watcher.submit("Failed.Release.torrent", []byte("data"), "tv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	submissionPath := path.Join(watcher.submissionsDirectory(), "tv", "Failed.Release.torrent")
+	blackholePath := path.Join(cfg.BlackholeDirectory, "Failed.Release.torrent")
+	err = os.WriteFile(blackholePath, []byte("data"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher.processUpload(submissionPath)
+	watcher.processUpload(blackholePath)
+
+	if _, err := os.Stat(submissionPath); !os.IsNotExist(err) {
+		t.Errorf("failed submission was kept: %v", err)
+	}
+	if _, err := os.Stat(blackholePath); err != nil {
+		t.Errorf("failed blackhole file was removed: %v", err)
+	}
+
+	// The release can be submitted again once the failed submission is gone
+	_, err = watcher.submit("Failed.Release.torrent", []byte("data"), "tv")
+	if err != nil {
+		t.Errorf("resubmitting the failed release: %s", err)
+	}
+}
+
+func TestCategoriesSurviveRestart(t *testing.T) {
+	cfg := newTestConfig(t)
+	client := &fakeClient{}
+	account := NewCloudAccount("Default", client)
+	manager, watcher := newTestServices(t, cfg, account)
+
+	_, err := watcher.submit("Some.Show.S01E01.magnet", []byte(testMagnet), "tv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.processUpload(path.Join(watcher.submissionsDirectory(), "tv", "Some.Show.S01E01.magnet"))
+	if created := client.getCreated(); len(created) != 1 {
+		t.Fatalf("submission was not uploaded, created %v", created)
+	}
+	manager.SetCategory("Other.Release", "movies")
+
+	// A restarted transfer manager reads the categories from the config directory
+	restarted, _ := newTestServices(t, cfg, account)
+	if category := restarted.takeCategory("Some Show S01E01"); category != "tv" {
+		t.Errorf("category of the uploaded submission after restart = %q, want tv", category)
+	}
+
+	restarted, _ = newTestServices(t, cfg, account)
+	if category := restarted.takeCategory("Some.Show.S01E01"); category != "" {
+		t.Errorf("taken category was kept across restarts: %q", category)
+	}
+	if category := restarted.takeCategory("Other.Release"); category != "movies" {
+		t.Errorf("category of Other.Release after restart = %q, want movies", category)
+	}
+	if category := restarted.takeCategory("Unknown.Release"); category != "" {
+		t.Errorf("unknown release has category %q", category)
+	}
+}
@@ -1,59 +1,140 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
-	"strconv"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/arr"
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/download_journal"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/events"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/metrics"
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/progress_downloader"
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/utils"
-	"github.com/ensingerphilipp/premiumizearr-nova/pkg/premiumizeme"
-	log "github.com/sirupsen/logrus"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/sirupsen/logrus"
 )
 
 type DownloadDetails struct {
 	Added              time.Time
 	Name               string
+	ItemID             string
+	Account            string
 	ProgressDownloader *progress_downloader.WriteCounter
 }
 
+// ImportDetails tracks an import command sent to an arr for a completed download
+type ImportDetails struct {
+	Added     time.Time
+	Name      string
+	ArrName   string
+	CommandID int64
+	Status    string
+	Message   string
+	arr       arr.IArr
+}
+
 type TransferManagerService struct {
-	premiumizemeClient *premiumizeme.Premiumizeme
-	arrsManager        *ArrsManagerService
-	config             *config.Config
-	lastUpdated        int64
-	transfers          []premiumizeme.Transfer
-	runningTask        bool
-	downloadListMutex  *sync.Mutex
-	downloadList       map[string]*DownloadDetails
-	status             string
-	downloadsFolderID  string
+	ctx    context.Context
+	cancel context.CancelFunc
+	// wg tracks the poll loop, the running downloads and the handling of errored transfers
+	wg                *sync.WaitGroup
+	accounts          []*CloudAccount
+	arrsManager       *ArrsManagerService
+	events            *events.Bus
+	config            *config.Config
+	lastUpdated       int64
+	transfers         []clouddownloader.Transfer
+	runningTask       bool
+	downloadListMutex *sync.Mutex
+	downloadList      map[string]*DownloadDetails
+	status            string
+	limiter           *progress_downloader.Limiter
+	bandwidthMutex    *sync.Mutex
+	bandwidthWindow   *config.BandwidthScheduleWindow
+	journal           *download_journal.Journal
+	importsMutex      *sync.Mutex
+	imports           []*ImportDetails
+	// directDownloads holds the queued and running direct downloads by their id
+	directDownloadsMutex *sync.Mutex
+	directDownloads      map[string]*directDownload
+	// failedDirectDownloads holds the blackhole files whose direct download failed, they are uploaded instead
+	failedDirectDownloads map[string]bool
+	// categories holds the category of submitted releases by their name until their download completed,
+	// they are saved to categoriesFileName so they survive a restart
+	categoriesMutex *sync.Mutex
+	categories      map[string]string
 }
 
+const (
+	journalFileName    = "downloads.journal.json"
+	categoriesFileName = "categories.json"
+	// partialDirectoryName is the directory inside the downloads directory folders are downloaded into
+	// before they are moved into place, so the arrs never import incomplete releases
+	partialDirectoryName = ".partial"
+	// maxVerifyAttempts is how often a file failing verification is downloaded before giving up
+	maxVerifyAttempts = 3
+	// maxImports is the number of import commands kept for display in the ui
+	maxImports = 50
+	// transferPollInterval is how often the transfers and downloads folders are polled
+	transferPollInterval = 15 * time.Second
+	// downloadProgressInterval is how often the progress of the running downloads is published
+	downloadProgressInterval = 2 * time.Second
+)
+
 // Handle
 func (t TransferManagerService) New() TransferManagerService {
-	t.premiumizemeClient = nil
+	t.ctx = nil
+	t.cancel = nil
+	t.wg = &sync.WaitGroup{}
+	t.accounts = nil
 	t.arrsManager = nil
+	t.events = nil
 	t.config = nil
 	t.lastUpdated = time.Now().Unix()
-	t.transfers = make([]premiumizeme.Transfer, 0)
+	t.transfers = make([]clouddownloader.Transfer, 0)
 	t.runningTask = false
 	t.downloadListMutex = &sync.Mutex{}
 	t.downloadList = make(map[string]*DownloadDetails, 0)
 	t.status = ""
-	t.downloadsFolderID = ""
+	t.limiter = nil
+	t.bandwidthMutex = &sync.Mutex{}
+	t.bandwidthWindow = nil
+	t.journal = nil
+	t.importsMutex = &sync.Mutex{}
+	t.imports = make([]*ImportDetails, 0)
+	t.directDownloadsMutex = &sync.Mutex{}
+	t.directDownloads = make(map[string]*directDownload)
+	t.failedDirectDownloads = make(map[string]bool)
+	t.categoriesMutex = &sync.Mutex{}
+	t.categories = make(map[string]string)
 	return t
 }
 
-func (t *TransferManagerService) Init(pme *premiumizeme.Premiumizeme, arrsManager *ArrsManagerService, config *config.Config) {
-	t.premiumizemeClient = pme
+func (t *TransferManagerService) Init(ctx context.Context, accounts []*CloudAccount, arrsManager *ArrsManagerService, bus *events.Bus, config *config.Config) {
+	t.ctx, t.cancel = context.WithCancel(ctx)
+	t.accounts = accounts
 	t.arrsManager = arrsManager
+	t.events = bus
 	t.config = config
+	t.limiter = progress_downloader.NewLimiter(speedLimitToBytesPerSecond(config.DownloadSpeedLimit))
+	t.ApplyBandwidthSchedule()
+
+	journal, err := download_journal.LoadJournal(path.Join(config.GetConfigDirectory(), journalFileName))
+	if err != nil {
+		log.Errorf("Error loading download journal, interrupted downloads will not be resumed: %s", err)
+	}
+	t.journal = journal
+	t.loadCategories()
+	metrics.SetDownloadsFunc(t.downloadStats)
+
 	t.CleanUpDownloadDirPeriod()
 }
 
@@ -75,11 +156,17 @@ func (t *TransferManagerService) CleanUpDownloadDirPeriod() {
 			return nil // Continue processing other files/directories
 		}
 
-		// Skip the base directory itself
-		if path == downloadBase {
+		// Skip the base directory itself and the staging directory, partial downloads are cleaned up individually
+		if path == downloadBase || path == filepath.Join(downloadBase, partialDirectoryName) {
 			return nil
 		}
 
+		// Keep interrupted downloads, they are resumed from the journal
+		if t.journal.ContainsPath(path) {
+			log.Debugf("Not cleaning %s, it belongs to an interrupted download", path)
+			return filepath.SkipDir
+		}
+
 		// Check if the file/directory is older than 4 days
 		if info.ModTime().Before(threshold) {
 			log.Infof("Deleting %s (last modified: %s)", path, info.ModTime())
@@ -120,17 +207,189 @@ func (manager *TransferManagerService) ConfigUpdatedCallback(currentConfig confi
 		log.Trace("Inside ConfigUpdatedCallback")
 		manager.CleanUpDownloadDir()
 	}
+
+	if currentConfig.DownloadSpeedLimit != newConfig.DownloadSpeedLimit {
+		log.Infof("Download speed limit changed from %d to %d Megabytes per second", currentConfig.DownloadSpeedLimit, newConfig.DownloadSpeedLimit)
+	}
+	manager.ApplyBandwidthSchedule()
 }
 
+// ApplyBandwidthSchedule sets the speed limit of all downloads from the currently active BandwidthSchedule window,
+// falling back to DownloadSpeedLimit outside of all windows
+func (manager *TransferManagerService) ApplyBandwidthSchedule() {
+	window := manager.config.GetActiveBandwidthWindow(time.Now())
+
+	manager.bandwidthMutex.Lock()
+	previous := manager.bandwidthWindow
+	manager.bandwidthWindow = window
+	manager.bandwidthMutex.Unlock()
+
+	if window == nil {
+		if previous != nil {
+			log.Infof("Bandwidth schedule window %s ended, limiting downloads to %d Megabytes per second", previous.Name, manager.config.DownloadSpeedLimit)
+		}
+		manager.limiter.SetLimit(speedLimitToBytesPerSecond(manager.config.DownloadSpeedLimit))
+		manager.limiter.SetPaused(false)
+		return
+	}
+
+	if previous == nil || previous.Name != window.Name {
+		if window.Paused {
+			log.Infof("Bandwidth schedule window %s started, pausing downloads", window.Name)
+		} else {
+			log.Infof("Bandwidth schedule window %s started, limiting downloads to %d Megabytes per second", window.Name, window.SpeedLimit)
+		}
+	}
+	manager.limiter.SetLimit(speedLimitToBytesPerSecond(window.SpeedLimit))
+	manager.limiter.SetPaused(window.Paused)
+}
+
+func (manager *TransferManagerService) segmentOptions() progress_downloader.SegmentOptions {
+	return progress_downloader.SegmentOptions{
+		Segments:    manager.config.DownloadSegmentsPerFile,
+		MinimumSize: int64(manager.config.DownloadSegmentMinimumSizeMB) * 1024 * 1024,
+	}
+}
+
+// GetBandwidthWindow returns the active BandwidthSchedule window, nil if none is active
+func (manager *TransferManagerService) GetBandwidthWindow() *config.BandwidthScheduleWindow {
+	manager.bandwidthMutex.Lock()
+	defer manager.bandwidthMutex.Unlock()
+	return manager.bandwidthWindow
+}
+
+// speedLimitToBytesPerSecond converts the DownloadSpeedLimit config value in Megabytes per second, 0 means unlimited
+func speedLimitToBytesPerSecond(megabytesPerSecond int) int64 {
+	return int64(megabytesPerSecond) * 1024 * 1024
+}
+
+// Start polls the cloud downloaders every transferPollInterval until Stop is called
+func (manager *TransferManagerService) Start() error {
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		manager.Run(transferPollInterval)
+	}()
+	manager.wg.Add(1)
+	go func() {
+		defer manager.wg.Done()
+		manager.publishDownloadProgress(downloadProgressInterval)
+	}()
+	return nil
+}
+
+// Stop stops polling and cancels the running downloads after journaling their progress, they are resumed on the next start
+func (manager *TransferManagerService) Stop(ctx context.Context) error {
+	log.Info("Stopping transfer manager...")
+	manager.checkpointDownloads()
+	manager.cancel()
+
+	stopped := utils.WaitContext(ctx, manager.wg)
+	err := manager.journal.Checkpoint()
+	if err != nil {
+		log.Errorf("Error writing download journal: %s", err)
+	}
+	if !stopped {
+		return fmt.Errorf("downloads did not stop in time: %w", ctx.Err())
+	}
+	return nil
+}
+
+// Run polls the cloud downloaders every interval until the context passed to Init is done or Stop is called
 func (manager *TransferManagerService) Run(interval time.Duration) {
-	manager.downloadsFolderID = utils.GetDownloadsFolderIDFromPremiumizeme(manager.premiumizemeClient)
-	for {
+	manager.ResumeJournaledDownloads()
+	for manager.ctx.Err() == nil {
+		started := time.Now()
 		manager.runningTask = true
+		manager.ApplyBandwidthSchedule()
 		manager.TaskUpdateTransfersList()
+		manager.TaskStartDirectDownloads()
 		manager.TaskCheckPremiumizeDownloadsFolder()
+		manager.TaskUpdateImportStatus()
+		manager.checkpointDownloads()
 		manager.runningTask = false
 		manager.lastUpdated = time.Now().Unix()
-		time.Sleep(interval)
+		metrics.PollDuration.Observe(time.Since(started).Seconds())
+		utils.SleepContext(manager.ctx, interval)
+	}
+	log.Info("Transfer manager stopped")
+}
+
+// ResumeJournaledDownloads restarts the downloads interrupted by a restart of the daemon,
+// folders no longer in the downloads folder of their account are dropped from the journal
+func (manager *TransferManagerService) ResumeJournaledDownloads() {
+	folders := manager.journal.GetFolders()
+	if len(folders) == 0 {
+		return
+	}
+
+	itemsByID := make(map[string]clouddownloader.Item)
+	accountsByID := make(map[string]*CloudAccount)
+	// listed are the accounts whose downloads folder was listed, folders of the others stay in the journal
+	listed := make(map[string]bool)
+	for _, account := range manager.accounts {
+		downloadsFolderID := account.GetDownloadsFolderID(manager.ctx)
+		if downloadsFolderID == "" {
+			log.Errorf("Cannot resume interrupted downloads of account %s without its downloads folder", account.Name)
+			continue
+		}
+
+		items, err := account.Client.ListFolder(manager.ctx, downloadsFolderID)
+		if err != nil {
+			log.Errorf("Error listing downloads folder of account %s, cannot resume interrupted downloads: %s", account.Name, err.Error())
+			continue
+		}
+
+		listed[account.Name] = true
+		for _, item := range items {
+			itemsByID[account.Name+"/"+item.ID] = item
+			accountsByID[account.Name+"/"+item.ID] = account
+		}
+	}
+
+	for _, folder := range folders {
+		if isDirectDownloadID(folder.ItemID) {
+			if _, err := os.Stat(folder.SourcePath); os.IsNotExist(err) {
+				// Nothing hands the release to the transfer manager again, the partial files would be kept forever
+				log.Infof("Blackhole file of interrupted direct download %s no longer exists, removing it from the journal", folder.Name)
+				manager.journal.RemoveFolder(folder.Account, folder.ItemID)
+				err = os.RemoveAll(folder.Path)
+				if err != nil {
+					log.Errorf("Error removing partial direct download %s: %s", folder.Name, err)
+				}
+				continue
+			}
+			log.Debugf("Interrupted direct download %s is resumed once its blackhole file is processed again", folder.Name)
+			continue
+		}
+
+		if !listed[folder.Account] {
+			continue
+		}
+
+		item, ok := itemsByID[folder.Account+"/"+folder.ItemID]
+		if !ok {
+			log.Infof("Interrupted download %s no longer exists on account %s, removing it from the journal", folder.Name, folder.Account)
+			manager.journal.RemoveFolder(folder.Account, folder.ItemID)
+			continue
+		}
+
+		log.Infof("Resuming interrupted download %s", folder.Name)
+		manager.HandleFinishedItem(accountsByID[folder.Account+"/"+folder.ItemID], item, manager.config.DownloadsDirectory)
+	}
+}
+
+// checkpointDownloads records the progress of all running downloads in the journal
+func (manager *TransferManagerService) checkpointDownloads() {
+	manager.downloadListMutex.Lock()
+	for _, download := range manager.downloadList {
+		manager.journal.UpdateProgress(download.Account, download.ItemID, int64(download.ProgressDownloader.GetBytesDownloaded()))
+	}
+	manager.downloadListMutex.Unlock()
+
+	err := manager.journal.Checkpoint()
+	if err != nil {
+		log.Errorf("Error writing download journal: %s", err)
 	}
 }
 
@@ -138,7 +397,7 @@ func (manager *TransferManagerService) GetDownloads() map[string]*DownloadDetail
 	return manager.downloadList
 }
 
-func (manager *TransferManagerService) GetTransfers() *[]premiumizeme.Transfer {
+func (manager *TransferManagerService) GetTransfers() *[]clouddownloader.Transfer {
 	return &manager.transfers
 }
 func (manager *TransferManagerService) GetStatus() string {
@@ -147,31 +406,55 @@ func (manager *TransferManagerService) GetStatus() string {
 
 func (manager *TransferManagerService) TaskUpdateTransfersList() {
 	log.Debug("Running Task UpdateTransfersList")
-	transfers, err := manager.premiumizemeClient.GetTransfers()
-	if err != nil {
-		log.Errorf("Error getting transfers: %s", err.Error())
-		return
+	transfers := make([]clouddownloader.Transfer, 0)
+	for _, account := range manager.accounts {
+		if starter, ok := account.Client.(clouddownloader.TransferStarter); ok {
+			err := starter.StartTransfers(manager.ctx)
+			if err != nil {
+				log.WithField("account", account.Name).WithError(err).Error("Error starting waiting transfers")
+			}
+		}
+
+		accountTransfers, err := account.Client.GetTransfers(manager.ctx)
+		if err != nil {
+			log.WithField("account", account.Name).WithError(err).Error("Error getting transfers")
+			continue
+		}
+		account.updateTransfers(accountTransfers)
+
+		for i := range accountTransfers {
+			accountTransfers[i].Account = account.Name
+		}
+		transfers = append(transfers, accountTransfers...)
+		manager.handleErroredTransfers(account, accountTransfers)
 	}
 	manager.updateTransfers(transfers)
+}
 
+// handleErroredTransfers marks errored transfers of account as failed in the arr that grabbed them
+func (manager *TransferManagerService) handleErroredTransfers(account *CloudAccount, transfers []clouddownloader.Transfer) {
 	log.Tracef("Checking %d transfers against %d Arr clients", len(transfers), len(manager.arrsManager.GetArrs()))
 	for _, transfer := range transfers {
+		transferLog := log.WithFields(logrus.Fields{"transfer_id": transfer.ID, "transfer": transfer.Name, "account": account.Name})
 		found := false
 		for _, arr := range manager.arrsManager.GetArrs() {
 			if found {
 				break
 			}
-			if transfer.Status == "error" {
-				log.Tracef("Checking errored transfer %s against %s history", transfer.Name, arr.GetArrName())
+			if transfer.Status == clouddownloader.TransferStatusError {
+				transferLog.WithField("arr", arr.GetArrName()).Trace("Checking errored transfer against arr history")
 				arrID, contains := arr.HistoryContains(transfer.Name)
 				if !contains {
-					log.Tracef("%s history doesn't contain %s", arr.GetArrName(), transfer.Name)
+					transferLog.WithField("arr", arr.GetArrName()).Trace("Arr history doesn't contain transfer")
 					continue
 				}
-				log.Tracef("Found %s in %s history", transfer.Name, arr.GetArrName())
 				found = true
-				log.Debugf("Processing transfer that has errored: %s", transfer.Name)
-				go arr.HandleErrorTransfer(&transfer, arrID, manager.premiumizemeClient)
+				transferLog.WithField("arr", arr.GetArrName()).Debug("Processing transfer that has errored")
+				manager.wg.Add(1)
+				go func() {
+					defer manager.wg.Done()
+					arr.HandleErrorTransfer(manager.ctx, &transfer, arrID, account.Client)
+				}()
 
 			}
 		}
@@ -181,38 +464,131 @@ func (manager *TransferManagerService) TaskUpdateTransfersList() {
 func (manager *TransferManagerService) TaskCheckPremiumizeDownloadsFolder() {
 	log.Debug("Running Task CheckPremiumizeDownloadsFolder")
 
-	items, err := manager.premiumizemeClient.ListFolder(manager.downloadsFolderID)
-	if err != nil {
-		log.Errorf("Error listing downloads folder: %s", err.Error())
+	if manager.limiter.IsPaused() {
+		log.Debug("Downloads are paused by the bandwidth schedule, not starting new downloads")
 		return
 	}
 
-	for _, item := range items {
-		if manager.countDownloads() < manager.config.SimultaneousDownloads {
-			log.Debugf("Processing completed item: %s", item.Name)
-			manager.HandleFinishedItem(item, manager.config.DownloadsDirectory)
-			//Sleep for one Second to let Asynchronous Downloads Start and Update
-			time.Sleep(time.Second * 1)
-		} else {
-			log.Debugf("Not processing any more transfers, %d are running and cap is %d", manager.countDownloads(), manager.config.SimultaneousDownloads)
-			break
+	for _, account := range manager.accounts {
+		downloadsFolderID := account.GetDownloadsFolderID(manager.ctx)
+		if downloadsFolderID == "" {
+			log.WithField("account", account.Name).Error("Downloads folder not found, skipping account")
+			continue
+		}
+
+		items, err := account.Client.ListFolder(manager.ctx, downloadsFolderID)
+		if err != nil {
+			log.WithField("account", account.Name).WithError(err).Error("Error listing downloads folder")
+			continue
+		}
+
+		for _, item := range items {
+			if manager.countDownloads() < manager.config.SimultaneousDownloads {
+				log.WithFields(logrus.Fields{"item": item.Name, "account": account.Name}).Debug("Processing completed item")
+				manager.HandleFinishedItem(account, item, manager.config.DownloadsDirectory)
+				//Sleep for one Second to let Asynchronous Downloads Start and Update
+				time.Sleep(time.Second * 1)
+			} else {
+				log.Debugf("Not processing any more transfers, %d are running and cap is %d", manager.countDownloads(), manager.config.SimultaneousDownloads)
+				return
+			}
 		}
 	}
 }
 
-func (manager *TransferManagerService) updateTransfers(transfers []premiumizeme.Transfer) {
+func (manager *TransferManagerService) updateTransfers(transfers []clouddownloader.Transfer) {
+	previousStatus := make(map[string]string, len(manager.transfers))
+	for _, transfer := range manager.transfers {
+		previousStatus[transfer.Account+"/"+transfer.ID] = transfer.Status
+	}
+	for _, transfer := range transfers {
+		previous := previousStatus[transfer.Account+"/"+transfer.ID]
+		if previous == transfer.Status {
+			continue
+		}
+		manager.events.Publish(events.TransferStatusChanged, events.TransferStatus{
+			ID:             transfer.ID,
+			Name:           transfer.Name,
+			Account:        transfer.Account,
+			Status:         transfer.Status,
+			PreviousStatus: previous,
+			Progress:       transfer.Progress,
+			Message:        transfer.Message,
+		})
+	}
 	manager.transfers = transfers
+
+	metrics.SetTransfers(transfers)
+}
+
+// publishDownloadProgress publishes the progress of the running downloads every interval until Stop is called
+func (manager *TransferManagerService) publishDownloadProgress(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-manager.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !manager.events.HasSubscribers() {
+			continue
+		}
+
+		manager.downloadListMutex.Lock()
+		progress := make([]events.Progress, 0, len(manager.downloadList))
+		for _, download := range manager.downloadList {
+			progress = append(progress, events.Progress{
+				Name:           download.Name,
+				Progress:       download.ProgressDownloader.GetProgress(),
+				Speed:          download.ProgressDownloader.GetSpeed(),
+				BytesPerSecond: download.ProgressDownloader.GetBytesPerSecond(),
+			})
+		}
+		manager.downloadListMutex.Unlock()
+
+		if len(progress) > 0 {
+			sort.Slice(progress, func(i, j int) bool {
+				return progress[i].Name < progress[j].Name
+			})
+			manager.events.Publish(events.DownloadProgress, progress)
+		}
+	}
+}
+
+// downloadStats returns the number of running downloads and their combined speed for the metrics
+func (manager *TransferManagerService) downloadStats() (int, float64) {
+	manager.downloadListMutex.Lock()
+	defer manager.downloadListMutex.Unlock()
+
+	speed := 0.0
+	for _, download := range manager.downloadList {
+		speed += download.ProgressDownloader.GetBytesPerSecond()
+	}
+	// Every download also has its parent folder in manager.downloadList
+	return len(manager.downloadList) / 2, speed
+}
+
+// downloadKey returns the key of the item itemID of account in manager.downloadList,
+// names are not unique as different releases contain files like sample.mkv
+func downloadKey(account string, itemID string) string {
+	return account + "/" + itemID
 }
 
-func (manager *TransferManagerService) addDownload(item *premiumizeme.Item) {
+// addDownload adds item of account to the running downloads and returns its details
+func (manager *TransferManagerService) addDownload(account string, item *clouddownloader.Item) *DownloadDetails {
 	manager.downloadListMutex.Lock()
 	defer manager.downloadListMutex.Unlock()
 
-	manager.downloadList[item.Name] = &DownloadDetails{
+	details := &DownloadDetails{
 		Added:              time.Now(),
 		Name:               item.Name,
+		ItemID:             item.ID,
+		Account:            account,
 		ProgressDownloader: progress_downloader.NewWriteCounter(),
 	}
+	manager.downloadList[downloadKey(account, item.ID)] = details
+	return details
 }
 
 func (manager *TransferManagerService) countDownloads() int {
@@ -222,80 +598,281 @@ func (manager *TransferManagerService) countDownloads() int {
 	return (len(manager.downloadList) / 2)
 }
 
-func (manager *TransferManagerService) removeDownload(name string) {
+func (manager *TransferManagerService) removeDownload(account string, itemID string) {
 	manager.downloadListMutex.Lock()
 	defer manager.downloadListMutex.Unlock()
 
-	delete(manager.downloadList, name)
+	delete(manager.downloadList, downloadKey(account, itemID))
 }
 
-func (manager *TransferManagerService) downloadExists(itemName string) bool {
+func (manager *TransferManagerService) downloadExists(account string, itemID string) bool {
 	manager.downloadListMutex.Lock()
 	defer manager.downloadListMutex.Unlock()
 
-	for _, dl := range manager.downloadList {
-		if dl.Name == itemName {
-			return true
-		}
-	}
-
-	return false
+	_, ok := manager.downloadList[downloadKey(account, itemID)]
+	return ok
 }
 
-func (manager *TransferManagerService) HandleFinishedItem(item premiumizeme.Item, downloadDirectory string) {
-	if manager.downloadExists(item.Name) {
-		log.Tracef("Transfer %s is already downloading", item.Name)
+func (manager *TransferManagerService) HandleFinishedItem(account *CloudAccount, item clouddownloader.Item, downloadDirectory string) {
+	itemLog := log.WithFields(logrus.Fields{"item_id": item.ID, "item": item.Name, "account": account.Name})
+	if manager.downloadExists(account.Name, item.ID) {
+		itemLog.Trace("Transfer is already downloading")
 		return
 	}
 
 	// If single Item is encountered (Torrent Download) it is moved into a new Folder with the Name of the Item to be downloaded during next refresh
-	if item.Type == "file" {
-		log.Tracef("Handling Item Type File in finished Transfer %s", item.Name)
+	if item.Type == clouddownloader.ItemTypeFile {
+		itemLog.Trace("Handling Item Type File in finished Transfer")
 
-		id, err := manager.premiumizemeClient.CreateFolder(item.Name+".folder", &manager.downloadsFolderID)
+		downloadsFolderID := account.GetDownloadsFolderID(manager.ctx)
+		id, err := account.Client.CreateFolder(manager.ctx, item.Name+".folder", &downloadsFolderID)
 		if err != nil {
-			log.Errorf("cannot create Folder for Single File Download! %+v", err)
+			itemLog.WithError(err).Error("cannot create Folder for Single File Download!")
 			return
 		}
 		var singleFileFolderID string = id
 
-		err = manager.premiumizemeClient.MoveItem(item.ID, singleFileFolderID)
+		err = account.Client.MoveItem(manager.ctx, item.ID, singleFileFolderID)
 		if err != nil {
-			log.Errorf("cannot move Single File to Folder for Download!  %+v", err)
+			itemLog.WithError(err).Error("cannot move Single File to Folder for Download!")
 			return
 		}
 
-		log.Infof("Single File moved to Folder for Download %s", item.Name)
+		itemLog.Info("Single File moved to Folder for Download")
 		return
 	}
 
-	if item.Type != "folder" {
-		log.Errorf("Item Type mismatch when trying to handle finished Transfer %s | %s", item.Name, item.Type)
+	if item.Type != clouddownloader.ItemTypeFolder {
+		itemLog.WithField("item_type", item.Type).Error("Item Type mismatch when trying to handle finished Transfer")
 		return
 	}
 
-	manager.addDownload(&item)
+	stagingDirectory := path.Join(downloadDirectory, partialDirectoryName)
+	err := os.MkdirAll(stagingDirectory, os.ModePerm)
+	if err != nil {
+		log.Errorf("Error creating staging directory %s: %s", stagingDirectory, err)
+		return
+	}
+
+	manager.addDownload(account.Name, &item)
+	manager.journal.AddFolder(account.Name, item.ID, item.Name, path.Join(stagingDirectory, item.Name))
+	manager.wg.Add(1)
 	go func() {
-		defer manager.removeDownload(item.Name)
-		err := manager.downloadFolderRecursively(item, downloadDirectory)
+		defer manager.wg.Done()
+		defer manager.removeDownload(account.Name, item.ID)
+		err := manager.downloadFolderRecursively(account, item.ID, item, stagingDirectory)
+		if err != nil && manager.ctx.Err() != nil {
+			itemLog.Info("Download interrupted, it is resumed on the next start")
+			return
+		}
 		if err != nil {
-			log.Errorf("Error downloading item %s: %s", item.Name, err)
-			manager.removeDownload(item.Name)
+			itemLog.WithError(err).Error("Error downloading item")
+			manager.publishDownloadError(item.Name, account.Name, err)
+			manager.removeDownload(account.Name, item.ID)
 			return
 		}
 
-		err = manager.premiumizemeClient.DeleteFolder(item.ID)
+		err = manager.completeDownload(item.Name, stagingDirectory, downloadDirectory)
 		if err != nil {
-			manager.removeDownload(item.Name)
-			log.Errorf("Error deleting folder on premiumize.me: %s", err)
+			itemLog.Error(err)
+			manager.publishDownloadError(item.Name, account.Name, err)
 			return
 		}
 
+		err = account.Client.DeleteFolder(manager.ctx, item.ID)
+		if err != nil {
+			manager.removeDownload(account.Name, item.ID)
+			itemLog.WithError(err).Error("Error deleting folder")
+			return
+		}
+
+		manager.journal.RemoveFolder(account.Name, item.ID)
+
 	}()
 }
 
-func (manager *TransferManagerService) downloadFolderRecursively(item premiumizeme.Item, downloadDirectory string) error {
-	items, err := manager.premiumizemeClient.ListFolder(item.ID)
+// completeDownload moves the verified download name out of stagingDirectory for the arrs to pick it up and triggers its import
+func (manager *TransferManagerService) completeDownload(name string, stagingDirectory string, downloadDirectory string) error {
+	if category := manager.takeCategory(name); category != "" {
+		downloadDirectory = path.Join(downloadDirectory, category)
+		err := os.MkdirAll(downloadDirectory, os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("error creating category directory %s: %w", downloadDirectory, err)
+		}
+	}
+
+	err := utils.MergeDirectory(path.Join(stagingDirectory, name), path.Join(downloadDirectory, name))
+	if err != nil {
+		return fmt.Errorf("error moving %s out of the staging directory: %w", name, err)
+	}
+	log.WithField("item", name).Info("Download completed")
+	manager.events.Publish(events.DownloadCompleted, events.Download{
+		Name: name,
+		Path: path.Join(downloadDirectory, name),
+	})
+	manager.importDownload(name, path.Join(downloadDirectory, name))
+	return nil
+}
+
+// publishDownloadError reports the failed download of name from account to the event subscribers
+func (manager *TransferManagerService) publishDownloadError(name string, account string, err error) {
+	manager.events.Publish(events.Failed, events.Failure{
+		Source:  "download",
+		Name:    name,
+		Account: account,
+		Message: err.Error(),
+	})
+}
+
+// SetCategory completes the download of the release name into the subdirectory category of the downloads directory
+func (manager *TransferManagerService) SetCategory(name string, category string) {
+	manager.categoriesMutex.Lock()
+	defer manager.categoriesMutex.Unlock()
+	manager.categories[name] = category
+	manager.saveCategories()
+}
+
+// takeCategory returns and forgets the category of the release name, the cloud downloader may name the transfer slightly differently
+func (manager *TransferManagerService) takeCategory(name string) string {
+	manager.categoriesMutex.Lock()
+	defer manager.categoriesMutex.Unlock()
+
+	for releaseName, category := range manager.categories {
+		if releaseName == name || arr.CompareFileNamesFuzzy(releaseName, name) {
+			delete(manager.categories, releaseName)
+			manager.saveCategories()
+			return category
+		}
+	}
+	return ""
+}
+
+// loadCategories reads the categories of the releases submitted before the last restart
+func (manager *TransferManagerService) loadCategories() {
+	filePath := path.Join(manager.config.GetConfigDirectory(), categoriesFileName)
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &manager.categories)
+	}
+	if err != nil {
+		log.Errorf("Error loading categories of submitted releases from %s: %s", filePath, err)
+		return
+	}
+	log.Debugf("Loaded %d categories of submitted releases from %s", len(manager.categories), filePath)
+}
+
+// saveCategories writes the categories to a temporary file which replaces the file, must be called with the mutex held
+func (manager *TransferManagerService) saveCategories() {
+	filePath := path.Join(manager.config.GetConfigDirectory(), categoriesFileName)
+	data, err := json.MarshalIndent(manager.categories, "", "  ")
+	if err != nil {
+		log.Errorf("Failed to marshal categories of submitted releases: %+v", err)
+		return
+	}
+
+	tmpPath := filePath + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0644)
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		log.Errorf("Failed to write categories of submitted releases to %s: %+v", filePath, err)
+	}
+}
+
+// importDownload tells the arr that grabbed name to import the completed download at downloadPath
+func (manager *TransferManagerService) importDownload(name string, downloadPath string) {
+	if manager.config.ArrImportMode == "" || manager.config.ArrImportMode == config.ImportModeDisabled {
+		return
+	}
+
+	for _, a := range manager.arrsManager.GetArrs() {
+		if _, contains := a.HistoryContains(name); !contains {
+			continue
+		}
+
+		details := &ImportDetails{
+			Added:   time.Now(),
+			Name:    name,
+			ArrName: a.GetArrName(),
+			arr:     a,
+		}
+
+		id, err := a.ImportDownload(manager.ctx, downloadPath, manager.config.ArrImportMode)
+		if err != nil {
+			log.WithFields(logrus.Fields{"item": name, "arr": a.GetArrName()}).WithError(err).Error("Error triggering import")
+			details.Status = "failed"
+			details.Message = err.Error()
+		} else {
+			log.WithFields(logrus.Fields{"item": name, "arr": a.GetArrName()}).Info("Triggered import")
+			details.CommandID = id
+			details.Status = "queued"
+		}
+
+		manager.importsMutex.Lock()
+		manager.imports = append(manager.imports, details)
+		if len(manager.imports) > maxImports {
+			manager.imports = manager.imports[len(manager.imports)-maxImports:]
+		}
+		manager.importsMutex.Unlock()
+		return
+	}
+
+	log.Debugf("No arr history contains %s, leaving the import to the arrs", name)
+}
+
+// importFinished holds the command statuses of the arrs after which an import is no longer polled
+var importFinished = map[string]bool{
+	"completed": true,
+	"failed":    true,
+	"aborted":   true,
+	"cancelled": true,
+	"orphaned":  true,
+}
+
+// TaskUpdateImportStatus refreshes the status of all import commands that have not finished yet
+func (manager *TransferManagerService) TaskUpdateImportStatus() {
+	manager.importsMutex.Lock()
+	pending := make([]*ImportDetails, 0)
+	for _, details := range manager.imports {
+		if !importFinished[details.Status] {
+			pending = append(pending, details)
+		}
+	}
+	manager.importsMutex.Unlock()
+
+	for _, details := range pending {
+		status, message, err := details.arr.GetCommandStatus(manager.ctx, details.CommandID)
+		if err != nil {
+			log.WithFields(logrus.Fields{"item": details.Name, "arr": details.ArrName}).WithError(err).Error("Error getting status of import")
+			continue
+		}
+
+		manager.importsMutex.Lock()
+		details.Status = status
+		details.Message = message
+		manager.importsMutex.Unlock()
+	}
+}
+
+// GetImports returns a copy of the recent import commands, newest first
+func (manager *TransferManagerService) GetImports() []ImportDetails {
+	manager.importsMutex.Lock()
+	defer manager.importsMutex.Unlock()
+
+	imports := make([]ImportDetails, 0, len(manager.imports))
+	for i := len(manager.imports) - 1; i >= 0; i-- {
+		imports = append(imports, *manager.imports[i])
+	}
+	return imports
+}
+
+// downloadFolderRecursively downloads item into downloadDirectory, rootFolderID is the id of the top level folder the files are journaled under
+func (manager *TransferManagerService) downloadFolderRecursively(account *CloudAccount, rootFolderID string, item clouddownloader.Item, downloadDirectory string) error {
+	items, err := account.Client.ListFolder(manager.ctx, item.ID)
 	if err != nil {
 		return fmt.Errorf("error listing folder items: %w", err)
 	}
@@ -304,32 +881,22 @@ func (manager *TransferManagerService) downloadFolderRecursively(item premiumize
 	err = os.Mkdir(savePath, os.ModePerm)
 	if err != nil {
 		log.Errorf("could not create save path: %s", err)
-		//		manager.removeDownload(item.Name)
+		//		manager.removeDownload(account.Name, item.ID)
 		//		return fmt.Errorf("error creating save path: %w", err)
 		//		no return due to os permissions sometime inaccurately throwing errors on different configurations
 	}
 
 	for _, item := range items {
-		if manager.downloadExists(item.Name) {
-			log.Tracef("Transfer %s is already downloading", item.Name)
-			return nil
+		if manager.downloadExists(account.Name, item.ID) {
+			return fmt.Errorf("file %s is already downloading", item.Name)
 		}
-		if item.Type == "file" {
-			manager.addDownload(&item)
-			link, err := manager.premiumizemeClient.GenerateFileLink(item.ID)
+		if item.Type == clouddownloader.ItemTypeFile {
+			err = manager.downloadFile(account, rootFolderID, item, savePath)
 			if err != nil {
-				log.Debugf("File Link Generation err: %s", err)
+				return err
 			}
-			var fileSavePath = path.Join(savePath, item.Name)
-			log.Trace("Downloading to: ", fileSavePath)
-			var ratelimit string = "--limit-rate=" + strconv.Itoa(manager.config.DownloadSpeedLimit) + "M"
-			err = progress_downloader.DownloadFile(ratelimit, link, fileSavePath, manager.downloadList[item.Name].ProgressDownloader)
-			if err != nil {
-				return fmt.Errorf("error downloading file %s: %w", item.Name, err)
-			}
-			manager.removeDownload(item.Name)
-		} else if item.Type == "folder" {
-			err = manager.downloadFolderRecursively(item, savePath)
+		} else if item.Type == clouddownloader.ItemTypeFolder {
+			err = manager.downloadFolderRecursively(account, rootFolderID, item, savePath)
 			if err != nil {
 				return fmt.Errorf("error downloading folder %s: %w", item.Name, err)
 			}
@@ -337,3 +904,58 @@ func (manager *TransferManagerService) downloadFolderRecursively(item premiumize
 	}
 	return nil
 }
+
+// downloadFile downloads a single file into savePath and verifies it against the size and hash reported by the cloud downloader,
+// files failing verification are downloaded again up to maxVerifyAttempts times
+func (manager *TransferManagerService) downloadFile(account *CloudAccount, rootFolderID string, item clouddownloader.Item, savePath string) error {
+	fileSavePath := path.Join(savePath, item.Name)
+
+	details, err := account.Client.GetItemDetails(manager.ctx, item.ID)
+	if err != nil {
+		return fmt.Errorf("error getting details of file %s: %w", item.Name, err)
+	}
+	if details.Size == 0 {
+		details.Size = item.Size
+	}
+
+	return manager.downloadVerifiedFile(account.Name, rootFolderID, item, details, fileSavePath)
+}
+
+// downloadVerifiedFile downloads the link of details to fileSavePath and verifies it, item is the file as listed in its folder
+func (manager *TransferManagerService) downloadVerifiedFile(account string, rootFolderID string, item clouddownloader.Item, details clouddownloader.Item, fileSavePath string) error {
+	if manager.journal.IsFileCompleted(account, item.ID) {
+		err := progress_downloader.VerifyFile(fileSavePath, details.Size, details.OpenSubtitlesHash)
+		if err == nil {
+			log.Tracef("File %s was already downloaded before, skipping", item.Name)
+			return nil
+		}
+		log.Warnf("Previously downloaded file %s failed verification, downloading again: %s", item.Name, err)
+		os.Remove(fileSavePath)
+	}
+
+	counter := manager.addDownload(account, &item).ProgressDownloader
+	defer manager.removeDownload(account, item.ID)
+	manager.journal.AddFile(account, rootFolderID, item.ID, item.Name, fileSavePath)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		log.Trace("Downloading to: ", fileSavePath)
+		err = progress_downloader.DownloadFileSegmented(manager.ctx, details.Link, fileSavePath, manager.segmentOptions(), manager.limiter, counter)
+		if err != nil {
+			return fmt.Errorf("error downloading file %s: %w", item.Name, err)
+		}
+
+		err = progress_downloader.VerifyFile(fileSavePath, details.Size, details.OpenSubtitlesHash)
+		if err == nil {
+			break
+		}
+		if attempt >= maxVerifyAttempts {
+			return fmt.Errorf("error verifying file %s after %d attempts: %w", item.Name, attempt, err)
+		}
+		log.Warnf("File %s failed verification, downloading again (attempt %d of %d): %s", item.Name, attempt, maxVerifyAttempts, err)
+		os.Remove(fileSavePath)
+	}
+
+	manager.journal.CompleteFile(account, item.ID, int64(counter.GetBytesDownloaded()))
+	return nil
+}
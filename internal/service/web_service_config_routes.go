@@ -34,7 +34,14 @@ func (s *WebServerService) ConfigHandler(w http.ResponseWriter, r *http.Request)
 			})
 			return
 		}
-		s.config.UpdateConfig(newConfig)
+		err = s.config.UpdateConfig(newConfig)
+		if err != nil {
+			EncodeAndWriteConfigChangeResponse(w, &ConfigChangeResponse{
+				Succeeded: false,
+				Status:    fmt.Sprintf("Config failed to update %s", err.Error()),
+			})
+			return
+		}
 		EncodeAndWriteConfigChangeResponse(w, &ConfigChangeResponse{
 			Succeeded: true,
 			Status:    "Config updated",
@@ -2,20 +2,40 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"path"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/ensingerphilipp/premiumizearr-nova/internal/config"
-	"github.com/ensingerphilipp/premiumizearr-nova/pkg/premiumizeme"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/events"
+	"github.com/ensingerphilipp/premiumizearr-nova/internal/logging"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/infohash"
+	"github.com/sirupsen/logrus"
 )
 
 type TransfersResponse struct {
-	Transfers []premiumizeme.Transfer `json:"data"`
-	Status    string                  `json:"status"`
+	Transfers []clouddownloader.Transfer `json:"data"`
+	Status    string                     `json:"status"`
 }
 
 func (s *WebServerService) TransfersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getTransfers(w)
+	case http.MethodPost:
+		s.submitTransfer(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *WebServerService) getTransfers(w http.ResponseWriter) {
 	var resp TransfersResponse
 	resp.Transfers = *s.transferManager.GetTransfers()
 	resp.Status = s.transferManager.GetStatus()
@@ -28,6 +48,112 @@ func (s *WebServerService) TransfersHandler(w http.ResponseWriter, r *http.Reque
 	w.Write(data)
 }
 
+// SubmitTransferRequest is the json body of a submitted magnet or link, files are submitted as multipart form
+// with the field file next to the optional fields magnet, link and category
+type SubmitTransferRequest struct {
+	Magnet   string `json:"magnet"`
+	Link     string `json:"link"`
+	Category string `json:"category"`
+}
+
+type SubmitTransferResponse struct {
+	Succeeded bool   `json:"succeeded"`
+	Status    string `json:"status"`
+	Name      string `json:"name"`
+}
+
+func (s *WebServerService) submitTransfer(w http.ResponseWriter, r *http.Request) {
+	if s.directoryWatcherService == nil {
+		writeSubmitTransferResponse(w, http.StatusServiceUnavailable, SubmitTransferResponse{Status: "Not Initialized"})
+		return
+	}
+
+	// Leave room for the multipart headers and the other fields next to the file
+	r.Body = http.MaxBytesReader(w, r.Body, MaxSubmissionSize+1<<20)
+
+	var req SubmitTransferRequest
+	var fileName string
+	var fileData []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := r.ParseMultipartForm(MaxSubmissionSize)
+		if err != nil {
+			writeSubmitTransferResponse(w, http.StatusBadRequest, SubmitTransferResponse{Status: err.Error()})
+			return
+		}
+		req.Magnet = r.FormValue("magnet")
+		req.Link = r.FormValue("link")
+		req.Category = r.FormValue("category")
+
+		file, header, err := r.FormFile("file")
+		if err == nil {
+			defer file.Close()
+			fileName = header.Filename
+			fileData, err = io.ReadAll(file)
+		} else if errors.Is(err, http.ErrMissingFile) {
+			err = nil
+		}
+		if err != nil {
+			writeSubmitTransferResponse(w, http.StatusBadRequest, SubmitTransferResponse{Status: err.Error()})
+			return
+		}
+	} else {
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			writeSubmitTransferResponse(w, http.StatusBadRequest, SubmitTransferResponse{Status: err.Error()})
+			return
+		}
+	}
+
+	submissions := 0
+	for _, submitted := range []bool{fileName != "", req.Magnet != "", req.Link != ""} {
+		if submitted {
+			submissions++
+		}
+	}
+	if submissions != 1 {
+		writeSubmitTransferResponse(w, http.StatusBadRequest, SubmitTransferResponse{Status: "Submit exactly one of file, magnet or link"})
+		return
+	}
+
+	var name string
+	var err error
+	switch {
+	case fileName != "":
+		name, err = s.directoryWatcherService.SubmitFile(fileName, fileData, req.Category)
+	case req.Magnet != "":
+		name, err = s.directoryWatcherService.SubmitMagnet(req.Magnet, req.Category)
+	default:
+		name, err = s.directoryWatcherService.SubmitLink(req.Link, req.Category)
+	}
+
+	switch {
+	case err == nil:
+		writeSubmitTransferResponse(w, http.StatusOK, SubmitTransferResponse{Succeeded: true, Status: "Added to Queue", Name: name})
+	case errors.Is(err, ErrSubmissionQueued):
+		writeSubmitTransferResponse(w, http.StatusConflict, SubmitTransferResponse{Status: err.Error()})
+	case errors.Is(err, ErrInvalidCategory), errors.Is(err, ErrInvalidSubmission), errors.Is(err, ErrInvalidLink),
+		errors.Is(err, ErrLinksNotSupported), errors.Is(err, infohash.ErrInvalidMagnet):
+		writeSubmitTransferResponse(w, http.StatusBadRequest, SubmitTransferResponse{Status: err.Error()})
+	case errors.Is(err, ErrNotStarted):
+		writeSubmitTransferResponse(w, http.StatusServiceUnavailable, SubmitTransferResponse{Status: err.Error()})
+	default:
+		log.Errorf("Error submitting transfer: %s", err)
+		writeSubmitTransferResponse(w, http.StatusInternalServerError, SubmitTransferResponse{Status: err.Error()})
+	}
+}
+
+func writeSubmitTransferResponse(w http.ResponseWriter, statusCode int, resp SubmitTransferResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(data)
+}
+
 type BlackholeFile struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -44,8 +170,9 @@ type Download struct {
 	Speed    string `json:"speed"`
 }
 type DownloadsResponse struct {
-	Downloads []Download `json:"data"`
-	Status    string     `json:"status"`
+	Downloads       []Download                      `json:"data"`
+	Status          string                          `json:"status"`
+	BandwidthWindow *config.BandwidthScheduleWindow `json:"bandwidth_window"`
 }
 
 func (s *WebServerService) DownloadsHandler(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +203,78 @@ func (s *WebServerService) DownloadsHandler(w http.ResponseWriter, r *http.Reque
 				Speed:    v.ProgressDownloader.GetSpeed(),
 			})
 		}
+		resp.BandwidthWindow = s.transferManager.GetBandwidthWindow()
+		if resp.BandwidthWindow == nil {
+			resp.Status = ""
+		} else if resp.BandwidthWindow.Paused {
+			resp.Status = fmt.Sprintf("Paused by bandwidth schedule %s", resp.BandwidthWindow.Name)
+		} else if resp.BandwidthWindow.SpeedLimit > 0 {
+			resp.Status = fmt.Sprintf("Limited to %d MB/s by bandwidth schedule %s", resp.BandwidthWindow.SpeedLimit, resp.BandwidthWindow.Name)
+		} else {
+			resp.Status = fmt.Sprintf("Unlimited by bandwidth schedule %s", resp.BandwidthWindow.Name)
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
+}
+
+type Import struct {
+	Added   int64  `json:"added"`
+	Name    string `json:"name"`
+	Arr     string `json:"arr"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+type ImportsResponse struct {
+	Imports []Import `json:"data"`
+	Status  string   `json:"status"`
+}
+
+func (s *WebServerService) ImportsHandler(w http.ResponseWriter, r *http.Request) {
+	var resp ImportsResponse
+
+	if s.transferManager == nil {
+		resp.Status = "Not Initialized"
+	} else {
+		for _, i := range s.transferManager.GetImports() {
+			resp.Imports = append(resp.Imports, Import{
+				Added:   i.Added.Unix(),
+				Name:    i.Name,
+				Arr:     i.ArrName,
+				Status:  i.Status,
+				Message: i.Message,
+			})
+		}
+		resp.Status = ""
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
+}
+
+type AccountResponse struct {
+	Accounts []AccountStatus `json:"data"`
+	Status   string          `json:"status"`
+}
+
+func (s *WebServerService) AccountHandler(w http.ResponseWriter, r *http.Request) {
+	var resp AccountResponse
+
+	if s.accountService == nil {
+		resp.Status = "Not Initialized"
+	} else {
+		resp.Accounts = s.accountService.GetAccounts()
 		resp.Status = ""
 	}
 
@@ -145,3 +344,130 @@ func (s *WebServerService) TestArrHandler(w http.ResponseWriter, r *http.Request
 
 	w.Write(data)
 }
+
+type LogsResponse struct {
+	Logs   []logging.Entry `json:"data"`
+	Status string          `json:"status"`
+}
+
+// logsHandler returns the recent log entries, filtered by the query parameters level (the least severe level
+// returned, trace by default) and subsystem. With stream=true the entries are sent as Server-Sent Events
+// followed by every new entry, until the client disconnects or shutdown is closed
+func (s *WebServerService) logsHandler(shutdown <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.logBuffer == nil {
+			http.Error(w, "Not Initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		level := logrus.TraceLevel
+		if l := r.URL.Query().Get("level"); l != "" {
+			parsed, err := logrus.ParseLevel(l)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			level = parsed
+		}
+		subsystem := r.URL.Query().Get("subsystem")
+
+		if r.URL.Query().Get("stream") != "true" {
+			resp := LogsResponse{
+				Logs:   s.logBuffer.Entries(0, level, subsystem),
+				Status: "ok",
+			}
+			data, err := json.Marshal(resp)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Write(data)
+			return
+		}
+
+		entries, ch, unsubscribe := s.logBuffer.Subscribe(lastEventID(r), level, subsystem)
+		defer unsubscribe()
+
+		stream, err := newEventStream(w)
+		if err != nil {
+			// Not logged, the entry would be sent to this stream again
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, entry := range entries {
+			if stream.Send(entry.ID, "log", entry) != nil {
+				return
+			}
+		}
+
+		keepAlive := time.NewTicker(eventStreamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-shutdown:
+				return
+			case <-keepAlive.C:
+				err = stream.KeepAlive()
+			case entry := <-ch:
+				if entry.Matches(level, subsystem) {
+					err = stream.Send(entry.ID, "log", entry)
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventsHandler sends the state changes published by the services as Server-Sent Events named after their type,
+// until the client disconnects or shutdown is closed. Events are not replayed, clients load the current state from
+// the other endpoints once connected. The query parameter types limits the stream to a comma separated list of types
+func (s *WebServerService) eventsHandler(shutdown <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.events == nil {
+			http.Error(w, "Not Initialized", http.StatusServiceUnavailable)
+			return
+		}
+
+		var types map[events.Type]bool
+		if t := r.URL.Query().Get("types"); t != "" {
+			types = make(map[events.Type]bool)
+			for _, eventType := range strings.Split(t, ",") {
+				types[events.Type(strings.TrimSpace(eventType))] = true
+			}
+		}
+
+		ch, unsubscribe := s.events.Subscribe()
+		defer unsubscribe()
+
+		stream, err := newEventStream(w)
+		if err != nil {
+			log.Errorf("Error starting event stream: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		keepAlive := time.NewTicker(eventStreamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-shutdown:
+				return
+			case <-keepAlive.C:
+				err = stream.KeepAlive()
+			case event := <-ch:
+				if types == nil || types[event.Type] {
+					err = stream.Send(event.ID, string(event.Type), event)
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
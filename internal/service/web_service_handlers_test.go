@@ -0,0 +1,143 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// multipartBody encodes fields and, unless fileName is empty, the file fileName with data as multipart form
+func multipartBody(t *testing.T, fields map[string]string, fileName string, data []byte) (string, *bytes.Buffer) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		err := writer.WriteField(name, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fileName != "" {
+		part, err := writer.CreateFormFile("file", fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writer.FormDataContentType(), body
+}
+
+func TestSubmitTransferHandler(t *testing.T) {
+	cfg := newTestConfig(t)
+	_, watcher := newTestServices(t, cfg, NewCloudAccount("Default", &fakeClient{}))
+	server := WebServerService{}.New()
+	server.directoryWatcherService = watcher
+
+	torrentType, torrentBody := multipartBody(t, map[string]string{"category": "tv"}, "Some.Show.S01E01.torrent", []byte("d4:infod4:name4:testee"))
+	emptyType, emptyBody := multipartBody(t, map[string]string{"category": "tv"}, "", nil)
+	bothType, bothBody := multipartBody(t, map[string]string{"magnet": testMagnet}, "Other.torrent", []byte("data"))
+	exeType, exeBody := multipartBody(t, nil, "release.exe", []byte("data"))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		release     string
+		savedAs     string
+	}{
+		{"magnet", "application/json", `{"magnet":"` + testMagnet + `&dn=Some.Release","category":"movies"}`, http.StatusOK, "Some.Release", "movies/Some.Release.magnet"},
+		{"link", "application/json", `{"link":"https://example.org/get/Other.Release"}`, http.StatusOK, "Other.Release", "Other.Release.link"},
+		{"file", torrentType, torrentBody.String(), http.StatusOK, "Some.Show.S01E01", "tv/Some.Show.S01E01.torrent"},
+		{"magnet queued twice", "application/json", `{"magnet":"` + testMagnet + `&dn=Some.Release","category":"movies"}`, http.StatusConflict, "", ""},
+		{"invalid magnet", "application/json", `{"magnet":"magnet:?dn=NoHash"}`, http.StatusBadRequest, "", ""},
+		{"invalid link", "application/json", `{"link":"ftp://example.org/release"}`, http.StatusBadRequest, "", ""},
+		{"invalid category", "application/json", `{"magnet":"` + testMagnet + `","category":"../tv"}`, http.StatusBadRequest, "", ""},
+		{"invalid json", "application/json", `{"magnet":`, http.StatusBadRequest, "", ""},
+		{"nothing submitted", emptyType, emptyBody.String(), http.StatusBadRequest, "", ""},
+		{"file and magnet", bothType, bothBody.String(), http.StatusBadRequest, "", ""},
+		{"unsupported file", exeType, exeBody.String(), http.StatusBadRequest, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/transfers", strings.NewReader(test.body))
+			request.Header.Set("Content-Type", test.contentType)
+			recorder := httptest.NewRecorder()
+			server.TransfersHandler(recorder, request)
+
+			if recorder.Code != test.status {
+				t.Errorf("expected status %d, got %d: %s", test.status, recorder.Code, recorder.Body.String())
+			}
+			var resp SubmitTransferResponse
+			err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("response is no json: %s", recorder.Body.String())
+			}
+			if resp.Succeeded != (test.status == http.StatusOK) || resp.Name != test.release {
+				t.Errorf("unexpected response %+v", resp)
+			}
+			if test.savedAs == "" {
+				return
+			}
+			if _, err := os.Stat(path.Join(watcher.submissionsDirectory(), test.savedAs)); err != nil {
+				t.Errorf("submission was not saved as %s: %s", test.savedAs, err)
+			}
+		})
+	}
+	if watcher.Queue.Len() != 3 {
+		t.Errorf("expected the 3 accepted submissions to be queued, got %d", watcher.Queue.Len())
+	}
+}
+
+func TestSubmitTransferHandlerLimits(t *testing.T) {
+	cfg := newTestConfig(t)
+	_, watcher := newTestServices(t, cfg)
+	server := WebServerService{}.New()
+
+	// Without a directory watcher nothing can be queued
+	request := httptest.NewRequest(http.MethodPost, "/api/transfers", strings.NewReader(`{"magnet":"`+testMagnet+`"}`))
+	recorder := httptest.NewRecorder()
+	server.TransfersHandler(recorder, request)
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d without directory watcher, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+
+	server.directoryWatcherService = watcher
+
+	// No account accepts links
+	request = httptest.NewRequest(http.MethodPost, "/api/transfers", strings.NewReader(`{"link":"https://example.org/release.nzb"}`))
+	recorder = httptest.NewRecorder()
+	server.TransfersHandler(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for a link without link support, got %d", http.StatusBadRequest, recorder.Code)
+	}
+
+	contentType, body := multipartBody(t, nil, "huge.torrent", make([]byte, MaxSubmissionSize+2<<20))
+	request = httptest.NewRequest(http.MethodPost, "/api/transfers", body)
+	request.Header.Set("Content-Type", contentType)
+	recorder = httptest.NewRecorder()
+	server.TransfersHandler(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an oversized file, got %d", http.StatusBadRequest, recorder.Code)
+	}
+
+	request = httptest.NewRequest(http.MethodPut, "/api/transfers", nil)
+	recorder = httptest.NewRecorder()
+	server.TransfersHandler(recorder, request)
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d for PUT, got %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+
+	if watcher.Queue.Len() != 0 {
+		t.Errorf("rejected submissions were queued: %d", watcher.Queue.Len())
+	}
+}
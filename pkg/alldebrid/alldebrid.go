@@ -0,0 +1,378 @@
+package alldebrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("subsystem", "alldebrid")
+
+const (
+	DefaultBaseURL = "https://api.alldebrid.com/v4"
+
+	// agent identifies the application to AllDebrid as required by the API
+	agent = "premiumizearr"
+
+	// DownloadsFolderID is the virtual folder holding the magnets created by the application,
+	// AllDebrid has no folders so each magnet is presented as a folder containing its files
+	DownloadsFolderID = "arrDownloads"
+)
+
+var (
+	ErrAPIKeyNotSet        = errors.New("alldebrid API key not set")
+	ErrUnsupportedFileType = errors.New("alldebrid only supports .magnet and .torrent files")
+	ErrNotSupported        = errors.New("operation not supported by alldebrid")
+	ErrNotOwned            = errors.New("magnet was not created by premiumizearr")
+)
+
+var _ clouddownloader.CloudDownloaderInterface = (*AllDebrid)(nil)
+
+type AllDebrid struct {
+	APIKey string
+	// BaseURL of the API, can be pointed at a fake server
+	BaseURL string
+	client  *http.Client
+	// owned are the magnets created by CreateTransfer, the only ones listed and deleted
+	owned *clouddownloader.OwnedTransfers
+}
+
+func NewAllDebridClient(APIKey string, owned *clouddownloader.OwnedTransfers) AllDebrid {
+	return AllDebrid{
+		APIKey:  APIKey,
+		BaseURL: DefaultBaseURL,
+		client:  &http.Client{Timeout: 60 * time.Second},
+		owned:   owned,
+	}
+}
+
+// doRequest sends an authenticated request to endpoint and decodes the data of the response into result if it is not nil
+func (ad *AllDebrid) doRequest(ctx context.Context, method string, endpoint string, query url.Values, contentType string, body io.Reader, result interface{}) error {
+	if ad.APIKey == "" {
+		return ErrAPIKeyNotSet
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(ad.BaseURL, "/") + endpoint)
+	if err != nil {
+		return err
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("agent", agent)
+	u.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+ad.APIKey)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	log.Tracef("AllDebrid request: %s %s", method, endpoint)
+	resp, err := ad.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res Response
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return fmt.Errorf("alldebrid error: %s: %w", resp.Status, err)
+	}
+
+	if res.Status != "success" {
+		if res.Error != nil {
+			return res.Error.err()
+		}
+		return fmt.Errorf("alldebrid error: %s", resp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Data, result)
+}
+
+// err converts the error details into an error wrapping the matching error shared by the cloud downloaders
+func (e *ErrorDetails) err() error {
+	err := fmt.Errorf("alldebrid error: %s (%s)", e.Message, e.Code)
+
+	var sentinel error
+	switch {
+	case e.Code == "MAGNET_TOO_MANY_ACTIVE":
+		sentinel = clouddownloader.ErrLimitReached
+	case e.Code == "MAGNET_INVALID_ID":
+		sentinel = clouddownloader.ErrNotFound
+	case strings.HasPrefix(e.Code, "AUTH_"):
+		sentinel = clouddownloader.ErrAuth
+	}
+	if sentinel != nil {
+		return fmt.Errorf("%w: %w", sentinel, err)
+	}
+	return err
+}
+
+// getMagnets lists the magnets created by the application
+func (ad *AllDebrid) getMagnets(ctx context.Context) ([]Magnet, error) {
+	var res MagnetStatusResponse
+	err := ad.doRequest(ctx, http.MethodGet, "/magnet/status", nil, "", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	var magnets []Magnet
+	if len(res.Magnets) > 0 {
+		err = json.Unmarshal(res.Magnets, &magnets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	owned := make([]Magnet, 0, len(magnets))
+	for _, magnet := range magnets {
+		if ad.owned.Contains(strconv.FormatInt(magnet.ID, 10)) {
+			owned = append(owned, magnet)
+		}
+	}
+	return owned, nil
+}
+
+func (ad *AllDebrid) getMagnet(ctx context.Context, id string) (Magnet, error) {
+	var res MagnetStatusResponse
+	err := ad.doRequest(ctx, http.MethodGet, "/magnet/status", url.Values{"id": {id}}, "", nil, &res)
+	if err != nil {
+		return Magnet{}, err
+	}
+
+	var magnet Magnet
+	err = json.Unmarshal(res.Magnets, &magnet)
+	return magnet, err
+}
+
+func (ad *AllDebrid) GetTransfers(ctx context.Context) ([]Transfer, error) {
+	log.Trace("Getting transfers list from alldebrid")
+	magnets, err := ad.getMagnets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]Transfer, 0, len(magnets))
+	for _, magnet := range magnets {
+		id := strconv.FormatInt(magnet.ID, 10)
+		transfer := Transfer{
+			ID:       id,
+			Name:     magnet.Filename,
+			Message:  magnet.Status,
+			Status:   transferStatus(magnet.StatusCode),
+			FolderID: id,
+		}
+		if magnet.StatusCode == StatusCodeReady {
+			transfer.Progress = 1
+		} else if magnet.Size > 0 {
+			transfer.Progress = float64(magnet.Downloaded) / float64(magnet.Size)
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	log.Tracef("Received %d transfers", len(transfers))
+	return transfers, nil
+}
+
+// transferStatus maps a magnet status code to the premiumize.me transfer status used across the application
+func transferStatus(statusCode int) string {
+	switch {
+	case statusCode == StatusCodeQueued:
+		return clouddownloader.TransferStatusQueued
+	case statusCode < StatusCodeReady:
+		return clouddownloader.TransferStatusRunning
+	case statusCode == StatusCodeReady:
+		return clouddownloader.TransferStatusFinished
+	default:
+		return clouddownloader.TransferStatusError
+	}
+}
+
+func (ad *AllDebrid) CreateTransfer(ctx context.Context, filePath string, parentID string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Errorf("First try failed, waiting 1 second and trying to open file: %s again", filePath)
+		time.Sleep(1 * time.Second)
+		data, err = os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var uploaded []UploadedMagnet
+	switch filepath.Ext(filePath) {
+	case ".magnet":
+		var res UploadMagnetsResponse
+		form := url.Values{"magnets[]": {strings.TrimSpace(string(data))}}
+		err = ad.doRequest(ctx, http.MethodPost, "/magnet/upload", nil, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()), &res)
+		uploaded = res.Magnets
+	case ".torrent":
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		var part io.Writer
+		part, err = writer.CreateFormFile("files[]", filepath.Base(filePath))
+		if err == nil {
+			_, err = part.Write(data)
+		}
+		if err != nil {
+			return err
+		}
+		writer.Close()
+
+		var res UploadFilesResponse
+		err = ad.doRequest(ctx, http.MethodPost, "/magnet/upload/file", nil, writer.FormDataContentType(), body, &res)
+		uploaded = res.Files
+	default:
+		return ErrUnsupportedFileType
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, magnet := range uploaded {
+		if magnet.Error != nil {
+			return magnet.Error.err()
+		}
+		log.Tracef("Transfer created: %+v", magnet)
+
+		err = ad.owned.Add(strconv.FormatInt(magnet.ID, 10))
+		if err != nil {
+			log.Errorf("Error recording alldebrid magnet %d as created by premiumizearr: %s", magnet.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteTransfer deletes the magnet id, magnets not created by the application are refused
+func (ad *AllDebrid) DeleteTransfer(ctx context.Context, id string) error {
+	if !ad.owned.Contains(id) {
+		return fmt.Errorf("failed to delete transfer: %s, message: %w", id, ErrNotOwned)
+	}
+
+	err := ad.doRequest(ctx, http.MethodGet, "/magnet/delete", url.Values{"id": {id}}, "", nil, nil)
+	if err != nil && !errors.Is(err, clouddownloader.ErrNotFound) {
+		return fmt.Errorf("failed to delete transfer: %s, message: %w", id, err)
+	}
+
+	err = ad.owned.Remove(id)
+	if err != nil {
+		log.Errorf("Error forgetting deleted alldebrid magnet %s: %s", id, err)
+	}
+	return nil
+}
+
+// GetFolders returns the virtual downloads folder
+func (ad *AllDebrid) GetFolders(ctx context.Context) ([]Item, error) {
+	if ad.APIKey == "" {
+		return nil, ErrAPIKeyNotSet
+	}
+	return []Item{{ID: DownloadsFolderID, Name: DownloadsFolderID, Type: clouddownloader.ItemTypeFolder}}, nil
+}
+
+// ListFolder lists the ready magnets created by the application for the downloads folder and the files of a magnet otherwise.
+// Files are identified by their locked link which is unlocked by GetItemDetails.
+func (ad *AllDebrid) ListFolder(ctx context.Context, folderID string) ([]Item, error) {
+	if folderID == DownloadsFolderID {
+		magnets, err := ad.getMagnets(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]Item, 0)
+		for _, magnet := range magnets {
+			if magnet.StatusCode != StatusCodeReady {
+				continue
+			}
+			items = append(items, Item{
+				ID:   strconv.FormatInt(magnet.ID, 10),
+				Name: magnet.Filename,
+				Type: clouddownloader.ItemTypeFolder,
+				Size: magnet.Size,
+			})
+		}
+		return items, nil
+	}
+
+	magnet, err := ad.getMagnet(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(magnet.Links))
+	for _, link := range magnet.Links {
+		items = append(items, Item{
+			ID:   link.Link,
+			Name: link.Filename,
+			Type: clouddownloader.ItemTypeFile,
+			Size: link.Size,
+		})
+	}
+	return items, nil
+}
+
+func (ad *AllDebrid) CreateFolder(ctx context.Context, folderName string, parentID *string) (string, error) {
+	if parentID == nil && folderName == DownloadsFolderID {
+		return DownloadsFolderID, nil
+	}
+	return "", ErrNotSupported
+}
+
+// DeleteFolder deletes the magnet folderID
+func (ad *AllDebrid) DeleteFolder(ctx context.Context, folderID string) error {
+	return ad.DeleteTransfer(ctx, folderID)
+}
+
+func (ad *AllDebrid) MoveItem(ctx context.Context, itemID string, folderID string) error {
+	return ErrNotSupported
+}
+
+func (ad *AllDebrid) GenerateFileLink(ctx context.Context, ID string) (string, error) {
+	item, err := ad.GetItemDetails(ctx, ID)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf("File link created: %+v", item.Link)
+	return item.Link, nil
+}
+
+// GetItemDetails unlocks the link ID into a direct download link
+func (ad *AllDebrid) GetItemDetails(ctx context.Context, ID string) (Item, error) {
+	log.Trace("Unlocking link: ", ID)
+	var res UnlockResponse
+	err := ad.doRequest(ctx, http.MethodGet, "/link/unlock", url.Values{"link": {ID}}, "", nil, &res)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return Item{
+		ID:   ID,
+		Name: res.Filename,
+		Type: clouddownloader.ItemTypeFile,
+		Link: res.Link,
+		Size: res.Filesize,
+	}, nil
+}
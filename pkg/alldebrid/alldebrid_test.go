@@ -0,0 +1,183 @@
+package alldebrid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+// fakeAPI is an AllDebrid API holding magnets, it records the requests changing the account
+type fakeAPI struct {
+	mutex   sync.Mutex
+	magnets []Magnet
+	writes  []string
+}
+
+func respond(w http.ResponseWriter, data interface{}) {
+	raw, _ := json.Marshal(data)
+	json.NewEncoder(w).Encode(Response{Status: "success", Data: raw})
+}
+
+func (api *fakeAPI) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /magnet/status", func(w http.ResponseWriter, r *http.Request) {
+		api.mutex.Lock()
+		defer api.mutex.Unlock()
+		magnets, _ := json.Marshal(api.magnets)
+		respond(w, MagnetStatusResponse{Magnets: magnets})
+	})
+	mux.HandleFunc("POST /magnet/upload", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("magnets[]") == "" {
+			t.Error("upload without magnet")
+		}
+		api.record(r)
+		respond(w, UploadMagnetsResponse{Magnets: []UploadedMagnet{{ID: 42}}})
+	})
+	mux.HandleFunc("POST /magnet/upload/file", func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("files[]")
+		if err != nil {
+			t.Errorf("upload without torrent file: %s", err)
+		} else if data, _ := io.ReadAll(file); string(data) != "torrent" {
+			t.Errorf("uploaded torrent file is %q", data)
+		}
+		api.record(r)
+		respond(w, UploadFilesResponse{Files: []UploadedMagnet{{ID: 42}}})
+	})
+	mux.HandleFunc("GET /magnet/delete", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		respond(w, nil)
+	})
+	return mux
+}
+
+func (api *fakeAPI) record(r *http.Request) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.writes = append(api.writes, r.URL.Path+"?"+r.URL.Query().Get("id"))
+}
+
+func newTestClient(t *testing.T, handler http.Handler, ownedIDs ...string) (*AllDebrid, *clouddownloader.OwnedTransfers) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	owned, err := clouddownloader.LoadOwnedTransfers(filepath.Join(t.TempDir(), "alldebrid.transfers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ownedIDs {
+		owned.Add(id)
+	}
+
+	client := NewAllDebridClient("key", owned)
+	client.BaseURL = server.URL
+	return &client, owned
+}
+
+func TestListFolderOnlyListsOwnedMagnets(t *testing.T) {
+	api := &fakeAPI{magnets: []Magnet{
+		{ID: 1, Filename: "Own", StatusCode: StatusCodeReady},
+		{ID: 2, Filename: "User", StatusCode: StatusCodeReady},
+		{ID: 3, Filename: "Running", StatusCode: StatusCodeDownloading},
+	}}
+	client, _ := newTestClient(t, api.handler(t), "1", "3")
+
+	items, err := client.ListFolder(context.Background(), DownloadsFolderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != "1" {
+		t.Errorf("expected only the ready owned magnet, got %+v", items)
+	}
+
+	transfers, err := client.GetTransfers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 2 {
+		t.Errorf("expected the 2 owned transfers, got %+v", transfers)
+	}
+}
+
+func TestCreateTransferRecordsMagnet(t *testing.T) {
+	tests := map[string]string{
+		"release.magnet":  "magnet:?xt=urn:btih:0123",
+		"release.torrent": "torrent",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := &fakeAPI{}
+			client, owned := newTestClient(t, api.handler(t))
+
+			filePath := filepath.Join(t.TempDir(), name)
+			err := os.WriteFile(filePath, []byte(content), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.CreateTransfer(context.Background(), filePath, DownloadsFolderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !owned.Contains("42") {
+				t.Error("created magnet was not recorded")
+			}
+		})
+	}
+}
+
+func TestDeleteFolderRefusesMagnetsOfUser(t *testing.T) {
+	api := &fakeAPI{}
+	client, owned := newTestClient(t, api.handler(t), "1")
+
+	err := client.DeleteFolder(context.Background(), "2")
+	if !errors.Is(err, ErrNotOwned) {
+		t.Errorf("expected ErrNotOwned deleting a magnet of the user, got %v", err)
+	}
+
+	err = client.DeleteFolder(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.writes) != 1 || api.writes[0] != "/magnet/delete?1" {
+		t.Errorf("expected only the owned magnet to be deleted, got %v", api.writes)
+	}
+	if owned.Contains("1") {
+		t.Error("deleted magnet is still recorded")
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := map[string]error{
+		"MAGNET_TOO_MANY_ACTIVE": clouddownloader.ErrLimitReached,
+		"MAGNET_INVALID_ID":      clouddownloader.ErrNotFound,
+		"AUTH_BAD_APIKEY":        clouddownloader.ErrAuth,
+		"MAGNET_INVALID_URI":     nil,
+	}
+	for code, want := range tests {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(Response{Status: "error", Error: &ErrorDetails{Code: code, Message: "failed"}})
+		})
+		client, _ := newTestClient(t, handler)
+
+		_, err := client.GetTransfers(context.Background())
+		if err == nil {
+			t.Errorf("%s: expected an error", code)
+			continue
+		}
+		if want != nil && !errors.Is(err, want) {
+			t.Errorf("%s: expected %v, got %v", code, want, err)
+		}
+		if want == nil && (errors.Is(err, clouddownloader.ErrNotFound) || errors.Is(err, clouddownloader.ErrAuth) || errors.Is(err, clouddownloader.ErrLimitReached)) {
+			t.Errorf("%s: expected an unclassified error, got %v", code, err)
+		}
+	}
+}
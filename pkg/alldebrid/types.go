@@ -0,0 +1,74 @@
+package alldebrid
+
+import (
+	"encoding/json"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+type Item = clouddownloader.Item
+type Transfer = clouddownloader.Transfer
+
+// Response is the envelope around every API response
+type Response struct {
+	Status string          `json:"status"`
+	Data   json.RawMessage `json:"data"`
+	Error  *ErrorDetails   `json:"error"`
+}
+
+type ErrorDetails struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type UploadedMagnet struct {
+	ID    int64         `json:"id"`
+	Name  string        `json:"name"`
+	Hash  string        `json:"hash"`
+	Ready bool          `json:"ready"`
+	Error *ErrorDetails `json:"error"`
+}
+
+type UploadMagnetsResponse struct {
+	Magnets []UploadedMagnet `json:"magnets"`
+}
+
+type UploadFilesResponse struct {
+	Files []UploadedMagnet `json:"files"`
+}
+
+type MagnetLink struct {
+	Link     string `json:"link"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+type Magnet struct {
+	ID         int64        `json:"id"`
+	Filename   string       `json:"filename"`
+	Size       int64        `json:"size"`
+	Status     string       `json:"status"`
+	StatusCode int          `json:"statusCode"`
+	Downloaded int64        `json:"downloaded"`
+	Links      []MagnetLink `json:"links"`
+}
+
+type MagnetStatusResponse struct {
+	// Magnets is a list when requesting all magnets and a single object when requesting one id
+	Magnets json.RawMessage `json:"magnets"`
+}
+
+type UnlockResponse struct {
+	Link     string `json:"link"`
+	Filename string `json:"filename"`
+	Filesize int64  `json:"filesize"`
+}
+
+// Magnet status codes, everything above StatusCodeReady is an error
+const (
+	StatusCodeQueued      = 0
+	StatusCodeDownloading = 1
+	StatusCodeCompressing = 2
+	StatusCodeUploading   = 3
+	StatusCodeReady       = 4
+)
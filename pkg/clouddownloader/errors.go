@@ -0,0 +1,12 @@
+package clouddownloader
+
+import "errors"
+
+// Errors cloud downloaders wrap so callers can react to them with errors.Is regardless of the provider
+var (
+	ErrLimitReached = errors.New("limit of transfers reached")
+	ErrDuplicate    = errors.New("transfer was already added")
+	ErrAuth         = errors.New("authentication failed")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+)
@@ -1,6 +1,66 @@
-package clouddownloader
-
-// Interface for the CloudDownloader interface
-type CloudDownloaderInterface interface {
-	GetTransfers() []Transfer
-}
+package clouddownloader
+
+import "context"
+
+// CloudDownloaderInterface is implemented by every cloud download provider.
+// Files are organised in folders, a provider without real folders has to emulate them.
+// Every method stops waiting for the API once ctx is done.
+type CloudDownloaderInterface interface {
+	// GetTransfers lists all transfers on the account, providers without folders only list the transfers they created
+	GetTransfers(ctx context.Context) ([]Transfer, error)
+	// CreateTransfer uploads a .nzb, .torrent or .magnet file, the result is saved to the folder parentID
+	CreateTransfer(ctx context.Context, filePath string, parentID string) error
+	// DeleteTransfer removes a transfer, files it already produced are kept
+	DeleteTransfer(ctx context.Context, id string) error
+
+	// GetFolders lists the root folder
+	GetFolders(ctx context.Context) ([]Item, error)
+	// ListFolder lists the content of folderID
+	ListFolder(ctx context.Context, folderID string) ([]Item, error)
+	// CreateFolder creates folderName below parentID, or the root folder when parentID is nil, and returns its ID
+	CreateFolder(ctx context.Context, folderName string, parentID *string) (string, error)
+	// DeleteFolder removes folderID including its content
+	DeleteFolder(ctx context.Context, folderID string) error
+	// MoveItem moves the file or folder itemID into folderID
+	MoveItem(ctx context.Context, itemID string, folderID string) error
+
+	// GenerateFileLink returns a direct download link for the file ID
+	GenerateFileLink(ctx context.Context, ID string) (string, error)
+	// GetItemDetails returns the details of the file ID including its download link and size
+	GetItemDetails(ctx context.Context, ID string) (Item, error)
+}
+
+// AccountInfoProvider is implemented by cloud downloaders reporting their quota
+type AccountInfoProvider interface {
+	GetAccountInfo(ctx context.Context) (AccountInfo, error)
+}
+
+// CacheChecker is implemented by cloud downloaders that can tell whether a release is available instantly
+type CacheChecker interface {
+	// CheckCache returns for every infohash or link in items whether it is cached
+	CheckCache(ctx context.Context, items []string) ([]bool, error)
+}
+
+// DirectDownloader is implemented by cloud downloaders that return the files of a cached release without creating a transfer
+type DirectDownloader interface {
+	// DirectDownload returns the files of the cached magnet link src
+	DirectDownload(ctx context.Context, src string) ([]DirectDownloadFile, error)
+}
+
+// TransferStarter is implemented by cloud downloaders whose transfers wait for an action before they start,
+// it is called before the transfers are listed so listing them never changes the account
+type TransferStarter interface {
+	// StartTransfers starts the waiting transfers created by the application
+	StartTransfers(ctx context.Context) error
+}
+
+// TransferSubmitter is implemented by cloud downloaders accepting transfers without a file on disk,
+// the transfer is saved to the folder parentID
+type TransferSubmitter interface {
+	// CreateMagnetTransfer creates a transfer of a magnet link
+	CreateMagnetTransfer(ctx context.Context, magnet string, parentID string) (CreatedTransfer, error)
+	// CreateLinkTransfer creates a transfer of a http or https link
+	CreateLinkTransfer(ctx context.Context, link string, parentID string) (CreatedTransfer, error)
+	// CreateFileTransfer creates a transfer of the content of a .torrent or .nzb file named fileName
+	CreateFileTransfer(ctx context.Context, fileName string, data []byte, parentID string) (CreatedTransfer, error)
+}
@@ -0,0 +1,102 @@
+package clouddownloader
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// OwnedTransfers records the IDs of the transfers a cloud downloader without folders created,
+// so it only lists and deletes those and leaves the transfers added by the user alone.
+// The IDs are saved to a file after every change. A nil OwnedTransfers owns nothing.
+type OwnedTransfers struct {
+	mutex sync.Mutex
+	path  string
+	ids   map[string]bool
+}
+
+// LoadOwnedTransfers reads the owned transfers from path, a missing file results in no owned transfers
+func LoadOwnedTransfers(path string) (*OwnedTransfers, error) {
+	o := &OwnedTransfers{
+		path: path,
+		ids:  make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return o, err
+	}
+
+	var ids []string
+	err = json.Unmarshal(data, &ids)
+	if err != nil {
+		return o, err
+	}
+	for _, id := range ids {
+		o.ids[id] = true
+	}
+	return o, nil
+}
+
+// Add records the transfer id as created by the application
+func (o *OwnedTransfers) Add(id string) error {
+	if o == nil {
+		return nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.ids[id] {
+		return nil
+	}
+	o.ids[id] = true
+	return o.save()
+}
+
+// Contains reports whether the transfer id was created by the application
+func (o *OwnedTransfers) Contains(id string) bool {
+	if o == nil {
+		return false
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.ids[id]
+}
+
+// Remove forgets the transfer id once it was deleted
+func (o *OwnedTransfers) Remove(id string) error {
+	if o == nil {
+		return nil
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if !o.ids[id] {
+		return nil
+	}
+	delete(o.ids, id)
+	return o.save()
+}
+
+// save writes the IDs to a temporary file which replaces the file, must be called with the mutex held
+func (o *OwnedTransfers) save() error {
+	ids := make([]string, 0, len(o.ids))
+	for id := range o.ids {
+		ids = append(ids, id)
+	}
+	data, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := o.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, o.path)
+}
@@ -0,0 +1,37 @@
+package clouddownloader
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOwnedTransfersPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfers.json")
+	owned, err := LoadOwnedTransfers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		err = owned.Add(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = owned.Remove("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadOwnedTransfers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Contains("a") || !reloaded.Contains("b") {
+		t.Errorf("expected only b to be owned after reloading")
+	}
+
+	var none *OwnedTransfers
+	if none.Contains("b") || none.Add("b") != nil {
+		t.Error("nil OwnedTransfers must own nothing")
+	}
+}
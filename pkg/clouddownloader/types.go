@@ -1,4 +1,72 @@
-package clouddownloader
-
-type Transfer struct {
-}
+package clouddownloader
+
+// Transfer is a download job running on the cloud downloader.
+// The json tags match the premiumize.me API as the web ui consumes transfers in that format.
+type Transfer struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Message  string  `json:"message"`
+	Status   string  `json:"status"`
+	Progress float64 `json:"progress"`
+	Src      string  `json:"src"`
+	FolderID string  `json:"folder_id"`
+	FileID   string  `json:"file_id"`
+	// Account the transfer belongs to, set by the transfer manager
+	Account string `json:"account,omitempty"`
+}
+
+// IsActive reports whether the transfer is still occupying a transfer slot
+func (t Transfer) IsActive() bool {
+	return t.Status == TransferStatusRunning || t.Status == TransferStatusQueued || t.Status == TransferStatusWaiting
+}
+
+// CreatedTransfer identifies a transfer that was just created
+type CreatedTransfer struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Item is a file or folder stored on the cloud downloader
+type Item struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	CreatedAt  int    `json:"created_at"`
+	MimeType   string `json:"mime_type"`
+	Link       string `json:"link"`
+	StreamLink string `json:"stream_link"`
+	// Size in bytes, only set for files
+	Size int64 `json:"size"`
+	// OpenSubtitlesHash is only set for video files
+	OpenSubtitlesHash string `json:"opensubtitles_hash"`
+}
+
+// DirectDownloadFile is a file of a cached release that can be downloaded without a transfer
+type DirectDownloadFile struct {
+	// Path of the file inside the release
+	Path string `json:"path"`
+	// Size in bytes
+	Size int64  `json:"size"`
+	Link string `json:"link"`
+}
+
+// AccountInfo holds the premium status and quota of an account
+type AccountInfo struct {
+	// PremiumUntil is the unix timestamp the premium subscription expires at
+	PremiumUntil int64 `json:"premium_until"`
+	// SpaceUsed in bytes
+	SpaceUsed float64 `json:"space_used"`
+	// LimitUsed is the fraction of the fair use limit used, from 0 to 1
+	LimitUsed float64 `json:"limit_used"`
+}
+
+const (
+	ItemTypeFile   = "file"
+	ItemTypeFolder = "folder"
+
+	TransferStatusError    = "error"
+	TransferStatusFinished = "finished"
+	TransferStatusRunning  = "running"
+	TransferStatusQueued   = "queued"
+	TransferStatusWaiting  = "waiting"
+)
@@ -0,0 +1,153 @@
+package infohash
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidTorrent   = errors.New("invalid torrent file")
+	ErrInvalidMagnet    = errors.New("invalid magnet link")
+	ErrUnsupportedFile  = errors.New("infohash can only be read from .torrent and .magnet files")
+	ErrNoInfoDictionary = errors.New("torrent file has no info dictionary")
+)
+
+// FromFile returns the lowercase hex infohash of a .torrent or .magnet file
+func FromFile(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	switch filepath.Ext(filePath) {
+	case ".torrent":
+		return FromTorrent(data)
+	case ".magnet":
+		return FromMagnet(strings.TrimSpace(string(data)))
+	default:
+		return "", ErrUnsupportedFile
+	}
+}
+
+// FromMagnet returns the lowercase hex infohash of the btih of a magnet link, base32 hashes are converted to hex
+func FromMagnet(magnet string) (string, error) {
+	u, err := url.Parse(magnet)
+	if err != nil || u.Scheme != "magnet" {
+		return "", ErrInvalidMagnet
+	}
+
+	for _, xt := range u.Query()["xt"] {
+		if !strings.HasPrefix(strings.ToLower(xt), "urn:btih:") {
+			continue
+		}
+		hash := xt[len("urn:btih:"):]
+		switch len(hash) {
+		case 40:
+			if _, err := hex.DecodeString(hash); err != nil {
+				return "", fmt.Errorf("%w: %s", ErrInvalidMagnet, err)
+			}
+			return strings.ToLower(hash), nil
+		case 32:
+			decoded, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+			if err != nil {
+				return "", fmt.Errorf("%w: %s", ErrInvalidMagnet, err)
+			}
+			return hex.EncodeToString(decoded), nil
+		default:
+			return "", fmt.Errorf("%w: btih has an unexpected length of %d", ErrInvalidMagnet, len(hash))
+		}
+	}
+
+	return "", fmt.Errorf("%w: no btih found", ErrInvalidMagnet)
+}
+
+// FromTorrent returns the lowercase hex SHA1 of the bencoded info dictionary of a torrent file
+func FromTorrent(data []byte) (string, error) {
+	if len(data) == 0 || data[0] != 'd' {
+		return "", ErrInvalidTorrent
+	}
+
+	// Walk the keys of the top level dictionary, the hash is taken over the raw bytes of the info value
+	pos := 1
+	for pos < len(data) && data[pos] != 'e' {
+		key, next, err := readString(data, pos)
+		if err != nil {
+			return "", err
+		}
+		end, err := skipValue(data, next)
+		if err != nil {
+			return "", err
+		}
+		if key == "info" {
+			sum := sha1.Sum(data[next:end])
+			return hex.EncodeToString(sum[:]), nil
+		}
+		pos = end
+	}
+
+	return "", ErrNoInfoDictionary
+}
+
+// readString reads the bencoded string at pos and returns it with the position after it
+func readString(data []byte, pos int) (string, int, error) {
+	colon := pos
+	for colon < len(data) && data[colon] != ':' {
+		colon++
+	}
+	if colon >= len(data) {
+		return "", 0, ErrInvalidTorrent
+	}
+
+	length, err := strconv.Atoi(string(data[pos:colon]))
+	// Compare against the remaining bytes, colon+1+length overflows for huge length prefixes
+	if err != nil || length < 0 || length > len(data)-colon-1 {
+		return "", 0, ErrInvalidTorrent
+	}
+
+	end := colon + 1 + length
+	return string(data[colon+1 : end]), end, nil
+}
+
+// skipValue returns the position after the bencoded value at pos
+func skipValue(data []byte, pos int) (int, error) {
+	if pos >= len(data) {
+		return 0, ErrInvalidTorrent
+	}
+
+	switch {
+	case data[pos] == 'i':
+		for pos < len(data) && data[pos] != 'e' {
+			pos++
+		}
+		if pos >= len(data) {
+			return 0, ErrInvalidTorrent
+		}
+		return pos + 1, nil
+	case data[pos] == 'l' || data[pos] == 'd':
+		pos++
+		for pos < len(data) && data[pos] != 'e' {
+			var err error
+			pos, err = skipValue(data, pos)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if pos >= len(data) {
+			return 0, ErrInvalidTorrent
+		}
+		return pos + 1, nil
+	case data[pos] >= '0' && data[pos] <= '9':
+		_, end, err := readString(data, pos)
+		return end, err
+	default:
+		return 0, ErrInvalidTorrent
+	}
+}
@@ -0,0 +1,124 @@
+package infohash
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// releaseHash is the infohash of testdata/release.torrent, a multi file torrent with trackers and web seeds
+const releaseHash = "22441b101748ffbc862312d8d3f5c355e740dd13"
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFromFile(t *testing.T) {
+	dir := t.TempDir()
+	magnetPath := filepath.Join(dir, "release.magnet")
+	err := os.WriteFile(magnetPath, []byte("magnet:?xt=urn:btih:"+releaseHash+"&dn=Some.Release\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nzbPath := filepath.Join(dir, "release.nzb")
+	err = os.WriteFile(nzbPath, []byte("<nzb></nzb>"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{filepath.Join("testdata", "release.torrent"), releaseHash, nil},
+		{magnetPath, releaseHash, nil},
+		{nzbPath, "", ErrUnsupportedFile},
+	}
+	for _, test := range tests {
+		got, err := FromFile(test.path)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("FromFile(%s): expected error %v, got %v", test.path, test.wantErr, err)
+		}
+		if got != test.want {
+			t.Errorf("FromFile(%s) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestFromTorrent(t *testing.T) {
+	info := "d6:lengthi42e4:name4:file12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+	nestedInfo := "d5:filesld6:lengthi1e4:pathl4:Subs7:Eng.srteed6:lengthi2e4:pathl4:infoeee4:name7:release12:piece lengthi16384e6:pieces20:bbbbbbbbbbbbbbbbbbbbe"
+
+	tests := []struct {
+		name    string
+		torrent string
+		want    string
+		wantErr error
+	}{
+		{"single file", "d8:announce14:http://tracker4:info" + info + "e", sha1Hex(info), nil},
+		{"nested info", "d4:info" + nestedInfo + "e", sha1Hex(nestedInfo), nil},
+		{"info key in an earlier dictionary", "d1:ad4:infod1:xi1eee4:info" + info + "e", sha1Hex(info), nil},
+		{"keys after info", "d4:info" + info + "8:url-listl3:urlee", sha1Hex(info), nil},
+		{"no info", "d8:announce14:http://trackere", "", ErrNoInfoDictionary},
+		{"empty", "", "", ErrInvalidTorrent},
+		{"not a dictionary", "l4:infoe", "", ErrInvalidTorrent},
+		{"truncated info", "d4:info" + info[:len(info)-10], "", ErrInvalidTorrent},
+		{"truncated integer", "d4:infod6:lengthi42", "", ErrInvalidTorrent},
+		{"truncated key", "d4:in", "", ErrInvalidTorrent},
+		{"length prefix past the end", "d4:infod4:name99:filee", "", ErrInvalidTorrent},
+		{"negative length prefix", "d4:infod4:name-1:ee", "", ErrInvalidTorrent},
+		{"overflowing length prefix", "d4:infod4:name99999999999999999999:filee", "", ErrInvalidTorrent},
+		{"maximum length prefix", "d4:infod4:name9223372036854775807:filee", "", ErrInvalidTorrent},
+		{"missing colon", "d4:infod4:name4filee", "", ErrInvalidTorrent},
+		{"unknown type", "d4:infox", "", ErrInvalidTorrent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := FromTorrent([]byte(test.torrent))
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("expected error %v, got %v", test.wantErr, err)
+			}
+			if got != test.want {
+				t.Errorf("FromTorrent = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFromMagnet(t *testing.T) {
+	tests := []struct {
+		name    string
+		magnet  string
+		want    string
+		wantErr error
+	}{
+		{"hex", "magnet:?xt=urn:btih:" + releaseHash + "&dn=Some.Release", releaseHash, nil},
+		{"uppercase hex", "magnet:?xt=urn:btih:22441B101748FFBC862312D8D3F5C355E740DD13", releaseHash, nil},
+		{"base32", "magnet:?xt=urn:btih:EJCBWEAXJD73ZBRDCLMNH5ODKXTUBXIT", releaseHash, nil},
+		{"lowercase base32", "magnet:?xt=urn:btih:ejcbweaxjd73zbrdclmnh5odkxtubxit", releaseHash, nil},
+		{"btih after other xt", "magnet:?xt=urn:sha1:ABC&xt=urn:btih:" + releaseHash, releaseHash, nil},
+		{"uppercase urn", "magnet:?xt=URN:BTIH:" + releaseHash, releaseHash, nil},
+		{"http link", "http://example.org/?xt=urn:btih:" + releaseHash, "", ErrInvalidMagnet},
+		{"no btih", "magnet:?dn=Some.Release", "", ErrInvalidMagnet},
+		{"invalid hex", "magnet:?xt=urn:btih:zz441b101748ffbc862312d8d3f5c355e740dd13", "", ErrInvalidMagnet},
+		{"invalid base32", "magnet:?xt=urn:btih:18CBWEAXJD73ZBRDCLMNH5ODKXTUBXIT", "", ErrInvalidMagnet},
+		{"unexpected length", "magnet:?xt=urn:btih:22441b", "", ErrInvalidMagnet},
+		{"invalid escape", "magnet:?xt=%zz", "", ErrInvalidMagnet},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := FromMagnet(test.magnet)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("expected error %v, got %v", test.wantErr, err)
+			}
+			if got != test.want {
+				t.Errorf("FromMagnet = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
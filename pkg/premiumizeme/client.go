@@ -0,0 +1,272 @@
+package premiumizeme
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("subsystem", "premiumizeme")
+
+const (
+	DefaultBaseURL   = "https://www.premiumize.me/api"
+	DefaultTimeout   = 60 * time.Second
+	DefaultUserAgent = "premiumizearr-nova"
+)
+
+// RetryPolicy retries GET requests failing with a transport error or answered with 429 or a 5xx status, waiting InitialBackoff
+// before the first retry and doubling the wait for every further retry up to MaxBackoff. A Retry-After header takes precedence.
+// Other requests may already have changed the account when they failed, they are only retried if the connection
+// could not be established or the API answered with 429.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy survives short outages of the API without holding up the callers for long
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+// backoff returns the wait before retry number attempt, starting at 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// Option configures a Premiumizeme client
+type Option func(*Premiumizeme)
+
+// WithBaseURL points the client at another API, e.g. a local mock server
+func WithBaseURL(baseURL string) Option {
+	return func(pm *Premiumizeme) {
+		pm.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithTimeout limits the duration of a single request including reading the response
+func WithTimeout(timeout time.Duration) Option {
+	return func(pm *Premiumizeme) {
+		pm.client.Timeout = timeout
+	}
+}
+
+// WithTransport replaces the http.RoundTripper used for requests
+func WithTransport(transport http.RoundTripper) Option {
+	return func(pm *Premiumizeme) {
+		pm.client.Transport = transport
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request
+func WithUserAgent(userAgent string) Option {
+	return func(pm *Premiumizeme) {
+		pm.userAgent = userAgent
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy, a policy with MaxRetries 0 disables retries
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(pm *Premiumizeme) {
+		pm.retryPolicy = policy
+	}
+}
+
+// RequestObserver is called once per API call with its endpoint and the final error after all retries, nil on success
+type RequestObserver func(endpoint string, err error)
+
+// WithRequestObserver reports every API call to observer, e.g. to count them
+func WithRequestObserver(observer RequestObserver) Option {
+	return func(pm *Premiumizeme) {
+		pm.observer = observer
+	}
+}
+
+// request is a call to the API, the body is kept as bytes so it can be sent again on a retry
+type request struct {
+	method      string
+	endpoint    string
+	query       url.Values
+	contentType string
+	body        []byte
+}
+
+// formRequest creates a POST request with values encoded as form
+func formRequest(endpoint string, values url.Values) request {
+	return request{
+		method:      http.MethodPost,
+		endpoint:    endpoint,
+		contentType: "application/x-www-form-urlencoded",
+		body:        []byte(values.Encode()),
+	}
+}
+
+func (pm *Premiumizeme) createPremiumizemeURL(urlPath string, query url.Values) (*url.URL, error) {
+	u, err := url.Parse(pm.baseURL + "/" + strings.TrimPrefix(urlPath, "/"))
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	for key, values := range query {
+		q[key] = values
+	}
+	q.Set("apikey", pm.APIKey)
+	u.RawQuery = q.Encode()
+	return u, nil
+}
+
+// doRequest sends req, retrying according to the retry policy, and decodes the response into result if it is not nil.
+// Responses with a status other than success are returned as error.
+func (pm *Premiumizeme) doRequest(ctx context.Context, req request, result interface{}) error {
+	err := pm.execute(ctx, req, result)
+	if pm.observer != nil {
+		pm.observer(req.endpoint, err)
+	}
+	return err
+}
+
+func (pm *Premiumizeme) execute(ctx context.Context, req request, result interface{}) error {
+	if pm.APIKey == "" {
+		return ErrAPIKeyNotSet
+	}
+
+	u, err := pm.createPremiumizemeURL(req.endpoint, req.query)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+		data, retryAfter, err = pm.send(ctx, req, u)
+		if err == nil {
+			break
+		}
+		if retryAfter < 0 || attempt >= pm.retryPolicy.MaxRetries {
+			return err
+		}
+
+		wait := pm.retryPolicy.backoff(attempt + 1)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		log.Debugf("premiumize.me request %s %s failed, retrying in %s: %s", req.method, req.endpoint, wait, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	var res SimpleResponse
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return fmt.Errorf("error decoding response of %s: %w", req.endpoint, err)
+	}
+	// Some endpoints like /item/details only report a status on errors
+	if res.Status != "" && res.Status != "success" {
+		message := res.Message
+		if message == "" {
+			message = fmt.Sprintf("error calling %s: %s", req.endpoint, res.Status)
+		}
+		return newAPIError(req.endpoint, http.StatusOK, message)
+	}
+
+	if result == nil {
+		return nil
+	}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return fmt.Errorf("error decoding response of %s: %w", req.endpoint, err)
+	}
+	return nil
+}
+
+// send sends req once and returns the response body. retryAfter is negative if the error must not be retried,
+// 0 if the backoff of the retry policy applies and the wait requested by the API otherwise.
+func (pm *Premiumizeme) send(ctx context.Context, req request, u *url.URL) ([]byte, time.Duration, error) {
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, req.method, u.String(), body)
+	if err != nil {
+		return nil, -1, err
+	}
+	if req.contentType != "" {
+		request.Header.Set("Content-Type", req.contentType)
+	}
+	if pm.userAgent != "" {
+		request.Header.Set("User-Agent", pm.userAgent)
+	}
+
+	idempotent := req.method == http.MethodGet
+
+	log.Tracef("premiumize.me request: %s %s", req.method, req.endpoint)
+	resp, err := pm.client.Do(request)
+	if err != nil {
+		if ctx.Err() != nil || (!idempotent && !isDialError(err)) {
+			return nil, -1, err
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if !idempotent {
+			return nil, -1, err
+		}
+		return nil, 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// Error responses usually still carry a message
+		var res SimpleResponse
+		json.Unmarshal(data, &res)
+		apiErr := newAPIError(req.endpoint, resp.StatusCode, res.Message)
+
+		if resp.StatusCode == http.StatusTooManyRequests || (idempotent && resp.StatusCode >= 500) {
+			return nil, parseRetryAfter(resp.Header.Get("Retry-After")), apiErr
+		}
+		return nil, -1, apiErr
+	}
+
+	return data, 0, nil
+}
+
+// isDialError reports whether err occurred while connecting, so the request was not sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// parseRetryAfter returns the wait requested by a Retry-After header in seconds, 0 if it is missing or a date
+func parseRetryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,191 @@
+package premiumizeme
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testRetryPolicy keeps the backoff short so the tests do not wait for it
+var testRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     4 * time.Millisecond,
+}
+
+// failingServer answers the first failures requests with status and the following ones with body
+func failingServer(t *testing.T, failures int32, status int, body string) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apikey") != "key" {
+			t.Errorf("request without api key: %s", r.URL)
+		}
+		if atomic.AddInt32(&requests, 1) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"status":"error","message":"failed"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newTestClient(server *httptest.Server) *Premiumizeme {
+	client := NewPremiumizemeClient("key", WithBaseURL(server.URL), WithRetryPolicy(testRetryPolicy))
+	return &client
+}
+
+func TestGetRetriesServerErrors(t *testing.T) {
+	server, requests := failingServer(t, 2, http.StatusBadGateway, `{"status":"success","transfers":[{"id":"1"}]}`)
+	client := newTestClient(server)
+
+	transfers, err := client.GetTransfers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 1 || *requests != 3 {
+		t.Errorf("expected the transfers after 3 requests, got %d transfers after %d requests", len(transfers), *requests)
+	}
+}
+
+func TestGetGivesUpAfterMaxRetries(t *testing.T) {
+	server, requests := failingServer(t, 10, http.StatusServiceUnavailable, "")
+	client := newTestClient(server)
+
+	_, err := client.GetTransfers(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the APIError of the last response, got %v", err)
+	}
+	if *requests != int32(testRetryPolicy.MaxRetries)+1 {
+		t.Errorf("expected %d requests, got %d", testRetryPolicy.MaxRetries+1, *requests)
+	}
+}
+
+func TestPostIsNotRetriedOnServerErrors(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusInternalServerError, `{"status":"success","id":"folder"}`)
+	client := newTestClient(server)
+
+	_, err := client.CreateFolder(context.Background(), "arrDownloads", nil)
+	if err == nil {
+		t.Error("expected the server error to be returned")
+	}
+	if *requests != 1 {
+		t.Errorf("folder creation was sent %d times", *requests)
+	}
+}
+
+func TestPostIsRetriedOnRateLimit(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusTooManyRequests, `{"status":"success","id":"folder"}`)
+	client := newTestClient(server)
+
+	id, err := client.CreateFolder(context.Background(), "arrDownloads", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "folder" || *requests != 2 {
+		t.Errorf("expected folder after 2 requests, got %q after %d requests", id, *requests)
+	}
+}
+
+func TestPostIsRetriedWhenConnectionFails(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	var attempts int32
+	counting := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	client := NewPremiumizemeClient("key", WithBaseURL("http://"+address), WithRetryPolicy(testRetryPolicy), WithTransport(counting))
+
+	_, err = client.CreateFolder(context.Background(), "arrDownloads", nil)
+	if err == nil {
+		t.Fatal("expected the connection error to be returned")
+	}
+	if attempts != int32(testRetryPolicy.MaxRetries)+1 {
+		t.Errorf("expected %d connection attempts, got %d", testRetryPolicy.MaxRetries+1, attempts)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestRetryAfterTakesPrecedence(t *testing.T) {
+	var requests int32
+	var first time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if waited := time.Since(first); waited < time.Second {
+			t.Errorf("retried after %s despite Retry-After of 1 second", waited)
+		}
+		w.Write([]byte(`{"status":"success","transfers":[]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := newTestClient(server).GetTransfers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expected := range want {
+		if got := policy.backoff(i + 1); got != expected {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, expected)
+		}
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := []struct {
+		status  int
+		message string
+		want    error
+	}{
+		{http.StatusUnauthorized, "", ErrAuth},
+		{http.StatusNotFound, "", ErrNotFound},
+		{http.StatusOK, messageLimitReached, ErrLimitReached},
+		{http.StatusOK, messageDuplicate, ErrDuplicate},
+		{http.StatusOK, "Not logged in", ErrAuth},
+		{http.StatusOK, "Folder does not exist", ErrNotFound},
+	}
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte(`{"status":"error","message":"` + test.message + `"}`))
+		}))
+
+		_, err := newTestClient(server).GetTransfers(context.Background())
+		if !errors.Is(err, test.want) {
+			t.Errorf("status %d message %q: expected %v, got %v", test.status, test.message, test.want, err)
+		}
+		server.Close()
+	}
+
+	client := NewPremiumizemeClient("")
+	_, err := client.GetTransfers(context.Background())
+	if !errors.Is(err, ErrAPIKeyNotSet) {
+		t.Errorf("expected ErrAPIKeyNotSet without api key, got %v", err)
+	}
+}
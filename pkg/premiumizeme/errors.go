@@ -0,0 +1,82 @@
+package premiumizeme
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+// Errors an APIError can be matched against with errors.Is, they are shared with the other cloud downloaders
+var (
+	ErrLimitReached = clouddownloader.ErrLimitReached
+	ErrDuplicate    = clouddownloader.ErrDuplicate
+	ErrAuth         = clouddownloader.ErrAuth
+	ErrNotFound     = clouddownloader.ErrNotFound
+	ErrRateLimited  = clouddownloader.ErrRateLimited
+)
+
+const (
+	messageLimitReached = "Limit of transfers reached!"
+	messageDuplicate    = "You already added this job."
+)
+
+// APIError is returned for requests the API answered with an error status or an error message
+type APIError struct {
+	Endpoint string
+	// StatusCode of the HTTP response, 200 for errors only reported in the response body
+	StatusCode int
+	// Message as returned by the API, may be empty
+	Message string
+	// Err is one of the sentinel errors above or nil if the error is not known
+	Err error
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("error calling %s: %s (%d)", e.Endpoint, http.StatusText(e.StatusCode), e.StatusCode)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError classifies an error response by its HTTP status and message
+func newAPIError(endpoint string, statusCode int, message string) *APIError {
+	return &APIError{
+		Endpoint:   endpoint,
+		StatusCode: statusCode,
+		Message:    message,
+		Err:        classifyError(statusCode, message),
+	}
+}
+
+func classifyError(statusCode int, message string) error {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+
+	lower := strings.ToLower(message)
+	switch {
+	case message == messageLimitReached:
+		return ErrLimitReached
+	case message == messageDuplicate:
+		return ErrDuplicate
+	case strings.Contains(lower, "not logged in") || strings.Contains(lower, "apikey") || strings.Contains(lower, "api key"):
+		return ErrAuth
+	case strings.Contains(lower, "not found") || strings.Contains(lower, "does not exist"):
+		return ErrNotFound
+	case strings.Contains(lower, "too many requests") || strings.Contains(lower, "rate limit"):
+		return ErrRateLimited
+	default:
+		return nil
+	}
+}
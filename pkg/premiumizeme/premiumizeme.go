@@ -1,665 +1,394 @@
-package premiumizeme
-
-import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"mime/multipart"
-	"net/http"
-	"net/url"
-	"os"
-	"path"
-	"path/filepath"
-	"strconv"
-	"strings"
-	"time"
-
-	log "github.com/sirupsen/logrus"
-)
-
-type Premiumizeme struct {
-	APIKey string
-}
-
-func NewPremiumizemeClient(APIKey string) Premiumizeme {
-	return Premiumizeme{APIKey: APIKey}
-}
-
-func (pm *Premiumizeme) createPremiumizemeURL(urlPath string) (url.URL, error) {
-	u, err := url.Parse("https://www.premiumize.me/api/")
-	if err != nil {
-		return *u, err
-	}
-	u.Path = path.Join(u.Path, urlPath)
-	q := u.Query()
-	q.Set("apikey", pm.APIKey)
-	u.RawQuery = q.Encode()
-	return *u, nil
-}
-
-var (
-	ErrAPIKeyNotSet = fmt.Errorf("premiumize.me API key not set")
-)
-
-func (pm *Premiumizeme) GetTransfers() ([]Transfer, error) {
-	if pm.APIKey == "" {
-		return nil, ErrAPIKeyNotSet
-	}
-
-	log.Trace("Getting transfers list from premiumize.me")
-	url, err := pm.createPremiumizemeURL("/transfer/list")
-	if err != nil {
-		return nil, err
-	}
-
-	var ret []Transfer
-	req, _ := http.NewRequest("GET", url.String(), nil)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return ret, err
-	}
-
-	defer resp.Body.Close()
-	res := ListTransfersResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	if res.Status != "success" {
-		return ret, fmt.Errorf("%s", res.Status)
-	}
-
-	if err != nil {
-		return ret, err
-	}
-
-	log.Tracef("Received %d transfers", len(res.Transfers))
-	return res.Transfers, nil
-}
-
-func (pm *Premiumizeme) ListFolder(folderID string) ([]Item, error) {
-	if pm.APIKey == "" {
-		return nil, ErrAPIKeyNotSet
-	}
-
-	var ret []Item
-	url, err := pm.createPremiumizemeURL("/folder/list")
-	if err != nil {
-		return ret, err
-	}
-
-	q := url.Query()
-	q.Set("id", folderID)
-	url.RawQuery = q.Encode()
-
-	client := &http.Client{}
-	request, err := http.NewRequest("GET", url.String(), nil)
-	if err != nil {
-		return ret, err
-	}
-
-	resp, err := client.Do(request)
-	if err != nil {
-		return ret, err
-	}
-
-	if resp.StatusCode != 200 {
-		return ret, fmt.Errorf("error listing folder: %s (%d)", resp.Status, resp.StatusCode)
-	}
-
-	defer resp.Body.Close()
-	res := ListFoldersResponse{}
-	log.Trace("Reading response")
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	if err != nil {
-		return ret, err
-	}
-
-	if res.Status != "success" {
-		return ret, fmt.Errorf(res.Message)
-	}
-
-	return res.Content, nil
-}
-
-func (pm *Premiumizeme) GetFolders() ([]Item, error) {
-	if pm.APIKey == "" {
-		return nil, ErrAPIKeyNotSet
-	}
-
-	log.Trace("Getting folder list from premiumize.me")
-	url, err := pm.createPremiumizemeURL("/folder/list")
-	if err != nil {
-		return nil, err
-	}
-
-	var ret []Item
-	req, _ := http.NewRequest("GET", url.String(), nil)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return ret, err
-	}
-
-	defer resp.Body.Close()
-	res := ListFoldersResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	if res.Status != "success" {
-		return ret, fmt.Errorf("%s", res.Status)
-	}
-
-	if err != nil {
-		return ret, err
-	}
-
-	log.Tracef("Received %d Folders", len(res.Content))
-	return res.Content, nil
-}
-
-func (pm *Premiumizeme) CreateTransfer(filePath string, parentID string) error {
-	if pm.APIKey == "" {
-		return ErrAPIKeyNotSet
-	}
-
-	//TODO: handle file size, i.e. incorrect file being saved
-	log.Trace("Opening file: ", filePath)
-	file, err := os.Open(filePath)
-	if err != nil {
-		log.Errorf("First try failed, waiting 1 second and trying to open file: %s again", filePath)
-		time.Sleep(1 * time.Second)
-		file, err = os.Open(filePath)
-		if err != nil {
-			return err
-		}
-	}
-	defer file.Close()
-
-	url, err := pm.createPremiumizemeURL("/transfer/create")
-	if err != nil {
-		return err
-	}
-
-	client := &http.Client{}
-	var request *http.Request
-
-	switch filepath.Ext(file.Name()) {
-	case ".nzb":
-		request, err = createNZBRequest(file, &url, parentID)
-	case ".magnet":
-		request, err = createMagnetRequest(file, &url, parentID)
-	case ".torrent":
-		request, err = createTorrentRequest(file, &url, parentID)
-	}
-
-	if err != nil {
-		return err
-	}
-
-	resp, err := client.Do(request)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("error creating transfer: %s (%d)", resp.Status, resp.StatusCode)
-	}
-
-	defer resp.Body.Close()
-	res := CreateTransferResponse{}
-	log.Trace("Reading response")
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	if err != nil {
-		return err
-	}
-
-	if res.Status != "success" {
-		return fmt.Errorf(res.Message)
-	}
-
-	log.Tracef("Transfer created: %+v", res)
-
-	return nil
-}
-
-func (pm *Premiumizeme) DeleteFolder(folderID string) error {
-	if pm.APIKey == "" {
-		return ErrAPIKeyNotSet
-	}
-
-	url, err := pm.createPremiumizemeURL("/folder/delete")
-	if err != nil {
-		return err
-	}
-
-	q := url.Query()
-	q.Set("id", folderID)
-	url.RawQuery = q.Encode()
-
-	client := &http.Client{}
-	request, err := http.NewRequest("DELETE", url.String(), nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := client.Do(request)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("error deleting folder: %s (%d)", resp.Status, resp.StatusCode)
-	}
-
-	defer resp.Body.Close()
-	res := SimpleResponse{}
-	log.Trace("Reading response")
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	if err != nil {
-		return err
-	}
-
-	if res.Status != "success" {
-		return fmt.Errorf(res.Message)
-	}
-
-	log.Tracef("Folder deleted: %+v", res)
-
-	return nil
-}
-
-func (pm *Premiumizeme) MoveItem(itemID string, folderID string) error {
-	if pm.APIKey == "" {
-		return ErrAPIKeyNotSet
-	}
-
-	url, err := pm.createPremiumizemeURL("/folder/paste")
-	if err != nil {
-		return err
-	}
-
-	q := url.Query()
-	q.Set("files[]", itemID)
-	q.Set("id", folderID)
-	url.RawQuery = q.Encode()
-
-	client := &http.Client{}
-	request, err := http.NewRequest("POST", url.String(), nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := client.Do(request)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("error moving single item to folder: %s (%d)", resp.Status, resp.StatusCode)
-	}
-
-	defer resp.Body.Close()
-	res := SimpleResponse{}
-	log.Trace("Reading response")
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	if err != nil {
-		return err
-	}
-
-	if res.Status != "success" {
-		return fmt.Errorf(res.Message)
-	}
-
-	log.Tracef("Item moved: %+v", res)
-
-	return nil
-}
-
-func (pm *Premiumizeme) CreateFolder(folderName string, parentID *string) (string, error) {
-	if pm.APIKey == "" {
-		return "", ErrAPIKeyNotSet
-	}
-
-	url, err := pm.createPremiumizemeURL("/folder/create")
-	if err != nil {
-		return "", err
-	}
-
-	q := url.Query()
-	q.Set("name", folderName)
-	if parentID != nil {
-		q.Set("parent_id", *parentID)
-	}
-	url.RawQuery = q.Encode()
-
-	client := &http.Client{}
-	request, err := http.NewRequest("POST", url.String(), nil)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := client.Do(request)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("error creating folder: %s (%d)", resp.Status, resp.StatusCode)
-	}
-
-	res := CreateFolderResponse{}
-	log.Trace("Reading response")
-	err = json.NewDecoder(resp.Body).Decode(&res)
-	if err != nil {
-		return "", err
-	}
-
-	if res.Status != "success" {
-		return "", fmt.Errorf(res.Message)
-	}
-
-	log.Tracef("Folder created: %+v", res)
-	return res.ID, nil
-}
-
-func (pm *Premiumizeme) DeleteTransfer(id string) error {
-	if pm.APIKey == "" {
-		return ErrAPIKeyNotSet
-	}
-
-	url, err := pm.createPremiumizemeURL("/transfer/delete")
-	if err != nil {
-		return err
-	}
-
-	client := &http.Client{}
-	request, err := createDeleteRequest(id, &url)
-	if err != nil {
-		return err
-	}
-
-	resp, err := client.Do(request)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("error deleting transfer: %s (%d)", resp.Status, resp.StatusCode)
-	}
-
-	defer resp.Body.Close()
-	res := SimpleResponse{}
-	log.Trace("Reading response")
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	if err != nil {
-		return err
-	}
-
-	if res.Status != "success" {
-		return fmt.Errorf("failed to delete transfer: %s, message: %+v", id, res.Message)
-	}
-
-	log.Tracef("Transfer Deleted: %+v", res)
-
-	return nil
-}
-
-func createNZBRequest(file *os.File, url *url.URL, parentID string) (*http.Request, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("src", filepath.Base(file.Name()))
-
-	if err != nil {
-		return nil, err
-	}
-
-	io.Copy(part, file)
-	writer.Close()
-
-	part, err = writer.CreateFormField("folder_id")
-
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = part.Write([]byte(parentID))
-
-	if err != nil {
-		return nil, err
-	}
-
-	request, err := http.NewRequest("POST", url.String(), body)
-	request.Header.Add("Content-Type", writer.FormDataContentType())
-
-	if err != nil {
-		return nil, err
-	}
-
-	return request, nil
-}
-
-func createMagnetRequest(file *os.File, url *url.URL, parentID string) (*http.Request, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormField("src")
-
-	if err != nil {
-		return nil, err
-	}
-
-	io.Copy(part, file)
-	writer.Close()
-
-	part, err = writer.CreateFormField("folder_id")
-
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = part.Write([]byte(parentID))
-
-	if err != nil {
-		return nil, err
-	}
-
-	request, err := http.NewRequest("POST", url.String(), body)
-	request.Header.Add("Content-Type", writer.FormDataContentType())
-
-	if err != nil {
-		return nil, err
-	}
-
-	return request, nil
-}
-
-func createTorrentRequest(file *os.File, url *url.URL, parentID string) (*http.Request, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("src", filepath.Base(file.Name()))
-
-	if err != nil {
-		return nil, err
-	}
-
-	io.Copy(part, file)
-	writer.Close()
-
-	part, err = writer.CreateFormField("folder_id")
-
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = part.Write([]byte(parentID))
-
-	if err != nil {
-		return nil, err
-	}
-
-	request, err := http.NewRequest("POST", url.String(), body)
-	request.Header.Add("Content-Type", writer.FormDataContentType())
-
-	if err != nil {
-		return nil, err
-	}
-
-	return request, nil
-}
-
-func createDeleteRequest(id string, URL *url.URL) (*http.Request, error) {
-	// Build Values to send to endpoint
-	data := url.Values{}
-	data.Set("id", id)
-
-	// Create and encode request
-	request, err := http.NewRequest("POST", URL.String(), strings.NewReader(data.Encode()))
-	if err != nil {
-		return nil, err
-	}
-
-	// Setup headers
-	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	request.Header.Add("Content-Length", strconv.Itoa(len(data.Encode())))
-
-	return request, nil
-}
-
-type SRCType = int
-
-const (
-	SRC_FILE = iota
-	SRC_FOLDER
-)
-
-func (pm *Premiumizeme) GenerateZippedFileLink(fileID string) (string, error) {
-	dlLink, err := pm.generateZip(fileID, SRC_FILE)
-	if err != nil {
-		return "", err
-	}
-	return dlLink, nil
-}
-
-func (pm *Premiumizeme) GenerateZippedFolderLink(fileID string) (string, error) {
-	dlLink, err := pm.generateZip(fileID, SRC_FOLDER)
-	if err != nil {
-		return "", err
-	}
-	return dlLink, nil
-}
-
-func (pm *Premiumizeme) generateZip(ID string, srcType SRCType) (string, error) {
-	if pm.APIKey == "" {
-		return "", ErrAPIKeyNotSet
-	}
-
-	// Build URL with apikey
-	URL, err := pm.createPremiumizemeURL("/zip/generate")
-	if err != nil {
-		return "", err
-	}
-
-	// Build Values to send to endpoint
-	data := url.Values{}
-
-	if srcType == SRC_FILE {
-		data.Set("files[]", ID)
-	} else if srcType == SRC_FOLDER {
-		data.Set("folders[]", ID)
-	} else {
-		return "", fmt.Errorf("unknown source type: %d", srcType)
-	}
-
-	// Create and encode request
-	request, err := http.NewRequest("POST", URL.String(), strings.NewReader(data.Encode()))
-	if err != nil {
-		return "", err
-	}
-
-	// Setup headers
-	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	request.Header.Add("Content-Length", strconv.Itoa(len(data.Encode())))
-
-	//Fire request
-	client := &http.Client{}
-	resp, err := client.Do(request)
-	if err != nil {
-		return "", err
-	}
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("error getting zip link for: %s response: %s (%d)", ID, resp.Status, resp.StatusCode)
-	}
-
-	// Decode response
-	defer resp.Body.Close()
-	var res GenerateZipResponse
-	log.Trace("Reading response")
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	log.Tracef("Zip Response: %+v", res)
-	if err != nil {
-		return "", err
-	}
-
-	if res.Status != "success" {
-		return "", fmt.Errorf("error getting zip link for: %s, Status: %s", ID, res.Status)
-	}
-
-	log.Debugf("Zip link created: %+v", res.Location)
-
-	return res.Location, nil
-}
-
-func (pm *Premiumizeme) GenerateFileLink(ID string) (string, error) {
-	if pm.APIKey == "" {
-		return "", ErrAPIKeyNotSet
-	}
-
-	// Build URL with apikey
-	log.Trace("Getting Download Link for Item: ", ID)
-	url, err := pm.createPremiumizemeURL("/item/details")
-	if err != nil {
-		return "", err
-	}
-
-	// Build Values to send to endpoint
-	q := url.Query()
-	q.Set("id", ID)
-	url.RawQuery = q.Encode()
-
-	client := &http.Client{}
-	request, err := http.NewRequest("GET", url.String(), nil)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := client.Do(request)
-	if err != nil {
-		return "", err
-	}
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("Error listing Item Details: %s (%d)", resp.Status, resp.StatusCode)
-	}
-
-	defer resp.Body.Close()
-	var res GenerateFileLinkResponse
-	err = json.NewDecoder(resp.Body).Decode(&res)
-
-	if res.Type != "file" {
-		return "", fmt.Errorf("Item Type was not File: %s", res.Type)
-	}
-
-	if err != nil {
-		return "Unknown Error: ", err
-	}
-
-	log.Debugf("File link created: %+v", res.Link)
-	return res.Link, nil
-}
+package premiumizeme
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+var _ clouddownloader.CloudDownloaderInterface = (*Premiumizeme)(nil)
+var _ clouddownloader.AccountInfoProvider = (*Premiumizeme)(nil)
+var _ clouddownloader.CacheChecker = (*Premiumizeme)(nil)
+var _ clouddownloader.DirectDownloader = (*Premiumizeme)(nil)
+var _ clouddownloader.TransferSubmitter = (*Premiumizeme)(nil)
+
+type Premiumizeme struct {
+	APIKey string
+
+	baseURL     string
+	userAgent   string
+	retryPolicy RetryPolicy
+	client      *http.Client
+	observer    RequestObserver
+}
+
+func NewPremiumizemeClient(APIKey string, options ...Option) Premiumizeme {
+	pm := Premiumizeme{
+		APIKey:      APIKey,
+		baseURL:     DefaultBaseURL,
+		userAgent:   DefaultUserAgent,
+		retryPolicy: DefaultRetryPolicy,
+		client:      &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, option := range options {
+		option(&pm)
+	}
+	return pm
+}
+
+var (
+	ErrAPIKeyNotSet  = fmt.Errorf("premiumize.me API key not set")
+	ErrInvalidSource = fmt.Errorf("invalid transfer source")
+)
+
+func (pm *Premiumizeme) GetTransfers(ctx context.Context) ([]Transfer, error) {
+	log.Trace("Getting transfers list from premiumize.me")
+	var res ListTransfersResponse
+	err := pm.doRequest(ctx, request{method: http.MethodGet, endpoint: "/transfer/list"}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Tracef("Received %d transfers", len(res.Transfers))
+	return res.Transfers, nil
+}
+
+// GetAccountInfo returns the premium expiry, used space and used fair use limit of the account
+func (pm *Premiumizeme) GetAccountInfo(ctx context.Context) (AccountInfo, error) {
+	log.Trace("Getting account info from premiumize.me")
+	var res AccountInfoResponse
+	err := pm.doRequest(ctx, request{method: http.MethodGet, endpoint: "/account/info"}, &res)
+	if err != nil {
+		return AccountInfo{}, fmt.Errorf("error getting account info: %w", err)
+	}
+
+	return AccountInfo{
+		PremiumUntil: res.PremiumUntil,
+		SpaceUsed:    res.SpaceUsed,
+		LimitUsed:    res.LimitUsed,
+	}, nil
+}
+
+// CheckCache returns for every infohash or link in items whether premiumize.me has it cached
+func (pm *Premiumizeme) CheckCache(ctx context.Context, items []string) ([]bool, error) {
+	log.Tracef("Checking cache of %d items on premiumize.me", len(items))
+	var res CacheCheckResponse
+	err := pm.doRequest(ctx, request{method: http.MethodGet, endpoint: "/cache/check", query: url.Values{"items[]": items}}, &res)
+	if err != nil {
+		return nil, fmt.Errorf("error checking cache: %w", err)
+	}
+
+	if len(res.Response) != len(items) {
+		return nil, fmt.Errorf("error checking cache: received %d results for %d items", len(res.Response), len(items))
+	}
+
+	return res.Response, nil
+}
+
+// DirectDownload returns the files of the cached magnet link src without creating a transfer
+func (pm *Premiumizeme) DirectDownload(ctx context.Context, src string) ([]DirectDownloadFile, error) {
+	log.Trace("Requesting direct download from premiumize.me")
+	var res DirectDownloadResponse
+	err := pm.doRequest(ctx, formRequest("/transfer/directdl", url.Values{"src": {src}}), &res)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting direct download: %w", err)
+	}
+
+	log.Tracef("Received %d files for direct download", len(res.Content))
+	return res.Content, nil
+}
+
+func (pm *Premiumizeme) ListFolder(ctx context.Context, folderID string) ([]Item, error) {
+	var res ListFoldersResponse
+	err := pm.doRequest(ctx, request{method: http.MethodGet, endpoint: "/folder/list", query: url.Values{"id": {folderID}}}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Content, nil
+}
+
+func (pm *Premiumizeme) GetFolders(ctx context.Context) ([]Item, error) {
+	log.Trace("Getting folder list from premiumize.me")
+	var res ListFoldersResponse
+	err := pm.doRequest(ctx, request{method: http.MethodGet, endpoint: "/folder/list"}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Tracef("Received %d Folders", len(res.Content))
+	return res.Content, nil
+}
+
+func (pm *Premiumizeme) CreateTransfer(ctx context.Context, filePath string, parentID string) error {
+	if pm.APIKey == "" {
+		return ErrAPIKeyNotSet
+	}
+
+	//TODO: handle file size, i.e. incorrect file being saved
+	log.Trace("Opening file: ", filePath)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Errorf("First try failed, waiting 1 second and trying to open file: %s again", filePath)
+		time.Sleep(1 * time.Second)
+		data, err = os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch filepath.Ext(filePath) {
+	case ".magnet":
+		_, err = pm.CreateMagnetTransfer(ctx, strings.TrimSpace(string(data)), parentID)
+	default:
+		_, err = pm.CreateFileTransfer(ctx, filepath.Base(filePath), data, parentID)
+	}
+	return err
+}
+
+// CreateMagnetTransfer creates a transfer of the magnet link magnet saved to the folder parentID
+func (pm *Premiumizeme) CreateMagnetTransfer(ctx context.Context, magnet string, parentID string) (CreatedTransfer, error) {
+	if !strings.HasPrefix(magnet, "magnet:") {
+		return CreatedTransfer{}, fmt.Errorf("%w: %s", ErrInvalidSource, "not a magnet link")
+	}
+
+	req, err := createTransferSrcRequest(magnet, parentID)
+	if err != nil {
+		return CreatedTransfer{}, err
+	}
+	return pm.createTransfer(ctx, req)
+}
+
+// CreateLinkTransfer creates a transfer of the http or https link saved to the folder parentID
+func (pm *Premiumizeme) CreateLinkTransfer(ctx context.Context, link string, parentID string) (CreatedTransfer, error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return CreatedTransfer{}, fmt.Errorf("%w: %s", ErrInvalidSource, "not a http link")
+	}
+
+	req, err := createTransferSrcRequest(link, parentID)
+	if err != nil {
+		return CreatedTransfer{}, err
+	}
+	return pm.createTransfer(ctx, req)
+}
+
+// CreateFileTransfer creates a transfer of the .torrent or .nzb file fileName with the content data saved to the folder parentID
+func (pm *Premiumizeme) CreateFileTransfer(ctx context.Context, fileName string, data []byte, parentID string) (CreatedTransfer, error) {
+	ext := filepath.Ext(fileName)
+	if ext != ".torrent" && ext != ".nzb" {
+		return CreatedTransfer{}, fmt.Errorf("%w: unsupported file type %s", ErrInvalidSource, ext)
+	}
+	if len(data) == 0 {
+		return CreatedTransfer{}, fmt.Errorf("%w: %s is empty", ErrInvalidSource, fileName)
+	}
+
+	req, err := createTransferFileRequest(fileName, data, parentID)
+	if err != nil {
+		return CreatedTransfer{}, err
+	}
+	return pm.createTransfer(ctx, req)
+}
+
+func (pm *Premiumizeme) createTransfer(ctx context.Context, req request) (CreatedTransfer, error) {
+	var res CreateTransferResponse
+	err := pm.doRequest(ctx, req, &res)
+	if err != nil {
+		return CreatedTransfer{}, err
+	}
+
+	log.Tracef("Transfer created: %+v", res)
+
+	return CreatedTransfer{ID: res.ID, Name: res.Name}, nil
+}
+
+func (pm *Premiumizeme) DeleteFolder(ctx context.Context, folderID string) error {
+	var res SimpleResponse
+	err := pm.doRequest(ctx, request{method: http.MethodDelete, endpoint: "/folder/delete", query: url.Values{"id": {folderID}}}, &res)
+	if err != nil {
+		return err
+	}
+
+	log.Tracef("Folder deleted: %+v", res)
+
+	return nil
+}
+
+func (pm *Premiumizeme) MoveItem(ctx context.Context, itemID string, folderID string) error {
+	var res SimpleResponse
+	query := url.Values{"files[]": {itemID}, "id": {folderID}}
+	err := pm.doRequest(ctx, request{method: http.MethodPost, endpoint: "/folder/paste", query: query}, &res)
+	if err != nil {
+		return err
+	}
+
+	log.Tracef("Item moved: %+v", res)
+
+	return nil
+}
+
+func (pm *Premiumizeme) CreateFolder(ctx context.Context, folderName string, parentID *string) (string, error) {
+	query := url.Values{"name": {folderName}}
+	if parentID != nil {
+		query.Set("parent_id", *parentID)
+	}
+
+	var res CreateFolderResponse
+	err := pm.doRequest(ctx, request{method: http.MethodPost, endpoint: "/folder/create", query: query}, &res)
+	if err != nil {
+		return "", err
+	}
+
+	log.Tracef("Folder created: %+v", res)
+	return res.ID, nil
+}
+
+func (pm *Premiumizeme) DeleteTransfer(ctx context.Context, id string) error {
+	var res SimpleResponse
+	err := pm.doRequest(ctx, formRequest("/transfer/delete", url.Values{"id": {id}}), &res)
+	if err != nil {
+		return fmt.Errorf("failed to delete transfer: %s, message: %w", id, err)
+	}
+
+	log.Tracef("Transfer Deleted: %+v", res)
+
+	return nil
+}
+
+// createTransferFileRequest uploads the content of a .nzb or .torrent file as src
+func createTransferFileRequest(fileName string, data []byte, parentID string) (request, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("src", fileName)
+	if err != nil {
+		return request{}, err
+	}
+
+	_, err = part.Write(data)
+	if err != nil {
+		return request{}, err
+	}
+
+	return finishTransferRequest(body, writer, parentID)
+}
+
+// createTransferSrcRequest submits a magnet or http link as src
+func createTransferSrcRequest(src string, parentID string) (request, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	err := writer.WriteField("src", src)
+	if err != nil {
+		return request{}, err
+	}
+
+	return finishTransferRequest(body, writer, parentID)
+}
+
+// finishTransferRequest adds the target folder to the multipart body of a transfer
+func finishTransferRequest(body *bytes.Buffer, writer *multipart.Writer, parentID string) (request, error) {
+	err := writer.WriteField("folder_id", parentID)
+	if err != nil {
+		return request{}, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return request{}, err
+	}
+
+	return request{
+		method:      http.MethodPost,
+		endpoint:    "/transfer/create",
+		contentType: writer.FormDataContentType(),
+		body:        body.Bytes(),
+	}, nil
+}
+
+type SRCType = int
+
+const (
+	SRC_FILE = iota
+	SRC_FOLDER
+)
+
+func (pm *Premiumizeme) GenerateZippedFileLink(ctx context.Context, fileID string) (string, error) {
+	dlLink, err := pm.generateZip(ctx, fileID, SRC_FILE)
+	if err != nil {
+		return "", err
+	}
+	return dlLink, nil
+}
+
+func (pm *Premiumizeme) GenerateZippedFolderLink(ctx context.Context, fileID string) (string, error) {
+	dlLink, err := pm.generateZip(ctx, fileID, SRC_FOLDER)
+	if err != nil {
+		return "", err
+	}
+	return dlLink, nil
+}
+
+func (pm *Premiumizeme) generateZip(ctx context.Context, ID string, srcType SRCType) (string, error) {
+	// Build Values to send to endpoint
+	data := url.Values{}
+
+	if srcType == SRC_FILE {
+		data.Set("files[]", ID)
+	} else if srcType == SRC_FOLDER {
+		data.Set("folders[]", ID)
+	} else {
+		return "", fmt.Errorf("unknown source type: %d", srcType)
+	}
+
+	var res GenerateZipResponse
+	err := pm.doRequest(ctx, formRequest("/zip/generate", data), &res)
+	log.Tracef("Zip Response: %+v", res)
+	if err != nil {
+		return "", fmt.Errorf("error getting zip link for: %s: %w", ID, err)
+	}
+
+	log.Debugf("Zip link created: %+v", res.Location)
+
+	return res.Location, nil
+}
+
+func (pm *Premiumizeme) GenerateFileLink(ctx context.Context, ID string) (string, error) {
+	item, err := pm.GetItemDetails(ctx, ID)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf("File link created: %+v", item.Link)
+	return item.Link, nil
+}
+
+// GetItemDetails returns the details of a file including its download link, size and hash
+func (pm *Premiumizeme) GetItemDetails(ctx context.Context, ID string) (Item, error) {
+	log.Trace("Getting Details for Item: ", ID)
+	var res GenerateFileLinkResponse
+	err := pm.doRequest(ctx, request{method: http.MethodGet, endpoint: "/item/details", query: url.Values{"id": {ID}}}, &res)
+	if err != nil {
+		return Item{}, fmt.Errorf("error listing item details: %w", err)
+	}
+
+	if res.Type != clouddownloader.ItemTypeFile {
+		return Item{}, fmt.Errorf("item type was not file: %s", res.Type)
+	}
+
+	return Item{
+		ID:                res.ID,
+		Name:              res.Name,
+		Type:              res.Type,
+		Link:              res.Link,
+		Size:              res.Size,
+		OpenSubtitlesHash: res.OpenSubtitlesHash,
+	}, nil
+}
@@ -0,0 +1,194 @@
+package premiumizeme
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// createdTransfer is a transfer/create request as received by transferServer
+type createdTransfer struct {
+	src      string
+	fileName string
+	data     string
+	folderID string
+}
+
+// transferServer answers transfer/create with response and records the multipart forms it received
+func transferServer(t *testing.T, response string) (*httptest.Server, func() []createdTransfer) {
+	var mutex sync.Mutex
+	var created []createdTransfer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/transfer/create" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		err := r.ParseMultipartForm(1 << 20)
+		if err != nil {
+			t.Errorf("transfer/create without multipart form: %s", err)
+			return
+		}
+
+		transfer := createdTransfer{src: r.FormValue("src"), folderID: r.FormValue("folder_id")}
+		if file, header, err := r.FormFile("src"); err == nil {
+			data, _ := io.ReadAll(file)
+			file.Close()
+			transfer.fileName = header.Filename
+			transfer.data = string(data)
+		}
+		mutex.Lock()
+		created = append(created, transfer)
+		mutex.Unlock()
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []createdTransfer {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]createdTransfer(nil), created...)
+	}
+}
+
+const createdResponse = `{"status":"success","id":"transfer","name":"Some.Release","type":"torrent"}`
+
+func TestCreateMagnetTransfer(t *testing.T) {
+	server, created := transferServer(t, createdResponse)
+	client := newTestClient(server)
+	magnet := "magnet:?xt=urn:btih:22441b101748ffbc862312d8d3f5c355e740dd13&dn=Some.Release"
+
+	transfer, err := client.CreateMagnetTransfer(context.Background(), magnet, "folder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.ID != "transfer" || transfer.Name != "Some.Release" {
+		t.Errorf("unexpected created transfer %+v", transfer)
+	}
+	if requests := created(); len(requests) != 1 || requests[0].src != magnet || requests[0].folderID != "folder" {
+		t.Errorf("expected the magnet to be submitted to folder, got %+v", requests)
+	}
+}
+
+func TestCreateLinkTransfer(t *testing.T) {
+	server, created := transferServer(t, createdResponse)
+	client := newTestClient(server)
+
+	_, err := client.CreateLinkTransfer(context.Background(), "https://example.org/Some.Release.nzb", "folder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests := created(); len(requests) != 1 || requests[0].src != "https://example.org/Some.Release.nzb" || requests[0].folderID != "folder" {
+		t.Errorf("expected the link to be submitted to folder, got %+v", requests)
+	}
+}
+
+func TestCreateFileTransfer(t *testing.T) {
+	server, created := transferServer(t, createdResponse)
+	client := newTestClient(server)
+
+	_, err := client.CreateFileTransfer(context.Background(), "Some.Release.torrent", []byte("d4:infod4:name4:testee"), "folder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := created()
+	if len(requests) != 1 || requests[0].fileName != "Some.Release.torrent" || requests[0].data != "d4:infod4:name4:testee" || requests[0].folderID != "folder" {
+		t.Errorf("expected the torrent file to be uploaded to folder, got %+v", requests)
+	}
+}
+
+func TestCreateTransferFromFile(t *testing.T) {
+	server, created := transferServer(t, createdResponse)
+	client := newTestClient(server)
+
+	dir := t.TempDir()
+	magnetPath := filepath.Join(dir, "Some.Release.magnet")
+	nzbPath := filepath.Join(dir, "Some.Release.nzb")
+	err := os.WriteFile(magnetPath, []byte("magnet:?xt=urn:btih:22441b101748ffbc862312d8d3f5c355e740dd13\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(nzbPath, []byte("<nzb></nzb>"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filePath := range []string{magnetPath, nzbPath} {
+		err = client.CreateTransfer(context.Background(), filePath, "folder")
+		if err != nil {
+			t.Fatalf("CreateTransfer(%s): %s", filePath, err)
+		}
+	}
+	requests := created()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 transfers, got %+v", requests)
+	}
+	if requests[0].src != "magnet:?xt=urn:btih:22441b101748ffbc862312d8d3f5c355e740dd13" || requests[0].fileName != "" {
+		t.Errorf("expected the trimmed magnet to be submitted as src, got %+v", requests[0])
+	}
+	if requests[1].fileName != "Some.Release.nzb" || requests[1].data != "<nzb></nzb>" {
+		t.Errorf("expected the nzb to be uploaded as file, got %+v", requests[1])
+	}
+}
+
+func TestCreateTransferRejectsInvalidSources(t *testing.T) {
+	server, created := transferServer(t, createdResponse)
+	client := newTestClient(server)
+	ctx := context.Background()
+
+	tests := map[string]func() error{
+		"magnet without magnet scheme": func() error {
+			_, err := client.CreateMagnetTransfer(ctx, "https://example.org/release", "folder")
+			return err
+		},
+		"link without http scheme": func() error {
+			_, err := client.CreateLinkTransfer(ctx, "ftp://example.org/release", "folder")
+			return err
+		},
+		"link without host": func() error {
+			_, err := client.CreateLinkTransfer(ctx, "https:///release", "folder")
+			return err
+		},
+		"unsupported file type": func() error {
+			_, err := client.CreateFileTransfer(ctx, "release.exe", []byte("data"), "folder")
+			return err
+		},
+		"empty file": func() error {
+			_, err := client.CreateFileTransfer(ctx, "release.torrent", nil, "folder")
+			return err
+		},
+	}
+	for name, create := range tests {
+		if err := create(); !errors.Is(err, ErrInvalidSource) {
+			t.Errorf("%s: expected ErrInvalidSource, got %v", name, err)
+		}
+	}
+	if requests := created(); len(requests) != 0 {
+		t.Errorf("invalid sources were sent to the api: %+v", requests)
+	}
+}
+
+func TestCreateTransferErrors(t *testing.T) {
+	tests := []struct {
+		response string
+		want     error
+	}{
+		{`{"status":"error","message":"You already added this job."}`, ErrDuplicate},
+		{`{"status":"error","message":"Limit of transfers reached!"}`, ErrLimitReached},
+	}
+	for _, test := range tests {
+		server, _ := transferServer(t, test.response)
+		client := newTestClient(server)
+
+		_, err := client.CreateMagnetTransfer(context.Background(), "magnet:?xt=urn:btih:22441b101748ffbc862312d8d3f5c355e740dd13", "folder")
+		if !errors.Is(err, test.want) {
+			t.Errorf("response %s: expected %v, got %v", test.response, test.want, err)
+		}
+	}
+}
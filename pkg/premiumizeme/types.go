@@ -1,79 +1,92 @@
-package premiumizeme
-
-type SimpleResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-}
-
-type GenerateZipResponse struct {
-	Status   string `json:"status"`
-	Location string `json:"location"`
-}
-
-type GenerateFileLinkResponse struct {
-	Status string `json:"status"`
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	Link   string `json:"link"`
-	Type   string `json:"type"`
-}
-
-type ListTransfersResponse struct {
-	Status    string     `json:"status"`
-	Transfers []Transfer `json:"transfers"`
-}
-
-type CreateTransferResponse struct {
-	Status  string `json:"status"`
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Type    string `json:"type"`
-	Message string `json:"message"`
-}
-
-type CreateFolderResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	ID      string `json:"id"`
-}
-
-type ListFoldersResponse struct {
-	Status   string `json:"status"`
-	Message  string `json:"message"`
-	Content  []Item `json:"content"`
-	Name     string `json:"name"`
-	ParentID string `json:"parent_id"`
-	FolderID string `json:"folder_id"`
-}
-
-type Item struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	Type       string `json:"type"`
-	CreatedAt  int    `json:"created_at"`
-	MimeType   string `json:"mime_type"`
-	Link       string `json:"link"`
-	StreamLink string `json:"stream_link"`
-}
-type FolderItems struct {
-	Status   string `json:"status"`
-	Contant  []Item `json:"content"`
-	Name     string `json:"name"`
-	ParentID string `json:"parent_id"`
-	FolderID string `json:"folder_id"`
-}
-
-type Transfer struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Message  string  `json:"message"`
-	Status   string  `json:"status"`
-	Progress float64 `json:"progress"`
-	Src      string  `json:"src"`
-	FolderID string  `json:"folder_id"`
-	FileID   string  `json:"file_id"`
-}
-
-const (
-	ERROR_FOLDER_ALREADY_EXISTS = "This folder already exists."
-)
+package premiumizeme
+
+import "github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+
+type SimpleResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type GenerateZipResponse struct {
+	Status   string `json:"status"`
+	Location string `json:"location"`
+}
+
+type GenerateFileLinkResponse struct {
+	Status            string `json:"status"`
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	Link              string `json:"link"`
+	Type              string `json:"type"`
+	Size              int64  `json:"size"`
+	OpenSubtitlesHash string `json:"opensubtitles_hash"`
+}
+
+type AccountInfoResponse struct {
+	Status       string  `json:"status"`
+	Message      string  `json:"message"`
+	CustomerID   string  `json:"customer_id"`
+	PremiumUntil int64   `json:"premium_until"`
+	LimitUsed    float64 `json:"limit_used"`
+	SpaceUsed    float64 `json:"space_used"`
+}
+
+type CacheCheckResponse struct {
+	Status   string   `json:"status"`
+	Message  string   `json:"message"`
+	Response []bool   `json:"response"`
+	Filename []string `json:"filename"`
+}
+
+type DirectDownloadResponse struct {
+	Status  string               `json:"status"`
+	Message string               `json:"message"`
+	Content []DirectDownloadFile `json:"content"`
+}
+
+type ListTransfersResponse struct {
+	Status    string     `json:"status"`
+	Transfers []Transfer `json:"transfers"`
+}
+
+type CreateTransferResponse struct {
+	Status  string `json:"status"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type CreateFolderResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	ID      string `json:"id"`
+}
+
+type ListFoldersResponse struct {
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	Content  []Item `json:"content"`
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id"`
+	FolderID string `json:"folder_id"`
+}
+
+// Item and Transfer are shared with the other cloud downloaders
+type Item = clouddownloader.Item
+type Transfer = clouddownloader.Transfer
+type AccountInfo = clouddownloader.AccountInfo
+type DirectDownloadFile = clouddownloader.DirectDownloadFile
+type CreatedTransfer = clouddownloader.CreatedTransfer
+
+type FolderItems struct {
+	Status   string `json:"status"`
+	Contant  []Item `json:"content"`
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id"`
+	FolderID string `json:"folder_id"`
+}
+
+const (
+	ERROR_FOLDER_ALREADY_EXISTS = "This folder already exists."
+)
@@ -0,0 +1,370 @@
+package realdebrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("subsystem", "realdebrid")
+
+const (
+	DefaultBaseURL = "https://api.real-debrid.com/rest/1.0"
+
+	// DownloadsFolderID is the virtual folder holding the torrents created by the application,
+	// Real-Debrid has no folders so each torrent is presented as a folder containing its files
+	DownloadsFolderID = "arrDownloads"
+)
+
+var (
+	ErrAPIKeyNotSet        = errors.New("real-debrid API key not set")
+	ErrUnsupportedFileType = errors.New("real-debrid only supports .magnet and .torrent files")
+	ErrNotSupported        = errors.New("operation not supported by real-debrid")
+	ErrNotOwned            = errors.New("torrent was not created by premiumizearr")
+)
+
+var (
+	_ clouddownloader.CloudDownloaderInterface = (*RealDebrid)(nil)
+	_ clouddownloader.TransferStarter          = (*RealDebrid)(nil)
+)
+
+type RealDebrid struct {
+	APIKey string
+	// BaseURL of the REST API, can be pointed at a fake server
+	BaseURL string
+	client  *http.Client
+	// owned are the torrents created by CreateTransfer, the only ones listed and deleted
+	owned *clouddownloader.OwnedTransfers
+}
+
+func NewRealDebridClient(APIKey string, owned *clouddownloader.OwnedTransfers) RealDebrid {
+	return RealDebrid{
+		APIKey:  APIKey,
+		BaseURL: DefaultBaseURL,
+		client:  &http.Client{Timeout: 60 * time.Second},
+		owned:   owned,
+	}
+}
+
+// doRequest sends an authenticated request to endpoint and decodes the JSON response into result if it is not nil
+func (rd *RealDebrid) doRequest(ctx context.Context, method string, endpoint string, contentType string, body io.Reader, result interface{}) error {
+	if rd.APIKey == "" {
+		return ErrAPIKeyNotSet
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(rd.BaseURL, "/")+endpoint, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+rd.APIKey)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	log.Tracef("Real-Debrid request: %s %s", method, endpoint)
+	resp, err := rd.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var res ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&res) == nil && res.Error != "" {
+			err = fmt.Errorf("real-debrid error: %s (%d)", res.Error, res.ErrorCode)
+		} else {
+			err = fmt.Errorf("real-debrid error: %s", resp.Status)
+		}
+		if sentinel := classifyError(resp.StatusCode, res.ErrorCode); sentinel != nil {
+			return fmt.Errorf("%w: %w", sentinel, err)
+		}
+		return err
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// classifyError maps an error response to the errors shared by the cloud downloaders, nil if it is not one of them
+func classifyError(statusCode int, errorCode int) error {
+	switch {
+	case errorCode == ErrorCodeTooManyActiveDownloads:
+		return clouddownloader.ErrLimitReached
+	case errorCode == ErrorCodeTooManyRequests || statusCode == http.StatusTooManyRequests:
+		return clouddownloader.ErrRateLimited
+	case errorCode == ErrorCodeBadToken || statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return clouddownloader.ErrAuth
+	case errorCode == ErrorCodeResourceNotFound || statusCode == http.StatusNotFound:
+		return clouddownloader.ErrNotFound
+	default:
+		return nil
+	}
+}
+
+func (rd *RealDebrid) postForm(ctx context.Context, endpoint string, values url.Values, result interface{}) error {
+	return rd.doRequest(ctx, http.MethodPost, endpoint, "application/x-www-form-urlencoded", strings.NewReader(values.Encode()), result)
+}
+
+// getTorrents lists the torrents created by the application
+func (rd *RealDebrid) getTorrents(ctx context.Context) ([]Torrent, error) {
+	var torrents []Torrent
+	err := rd.doRequest(ctx, http.MethodGet, "/torrents?limit=2500", "", nil, &torrents)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make([]Torrent, 0, len(torrents))
+	for _, torrent := range torrents {
+		if rd.owned.Contains(torrent.ID) {
+			owned = append(owned, torrent)
+		}
+	}
+	return owned, nil
+}
+
+func (rd *RealDebrid) getTorrentInfo(ctx context.Context, id string) (TorrentInfo, error) {
+	var info TorrentInfo
+	err := rd.doRequest(ctx, http.MethodGet, "/torrents/info/"+url.PathEscape(id), "", nil, &info)
+	return info, err
+}
+
+// selectAllFiles starts a torrent waiting for the file selection
+func (rd *RealDebrid) selectAllFiles(ctx context.Context, id string) error {
+	return rd.postForm(ctx, "/torrents/selectFiles/"+url.PathEscape(id), url.Values{"files": {"all"}}, nil)
+}
+
+func (rd *RealDebrid) GetTransfers(ctx context.Context) ([]Transfer, error) {
+	log.Trace("Getting transfers list from real-debrid")
+	torrents, err := rd.getTorrents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make([]Transfer, 0, len(torrents))
+	for _, torrent := range torrents {
+		transfers = append(transfers, Transfer{
+			ID:       torrent.ID,
+			Name:     torrent.Filename,
+			Message:  torrent.Status,
+			Status:   transferStatus(torrent.Status),
+			Progress: torrent.Progress / 100,
+			FolderID: torrent.ID,
+		})
+	}
+
+	log.Tracef("Received %d transfers", len(transfers))
+	return transfers, nil
+}
+
+// StartTransfers selects all files of the torrents still waiting for the file selection
+func (rd *RealDebrid) StartTransfers(ctx context.Context) error {
+	torrents, err := rd.getTorrents(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, torrent := range torrents {
+		if torrent.Status != StatusWaitingFilesSelection {
+			continue
+		}
+		log.Debugf("Selecting all files of real-debrid torrent %s", torrent.Filename)
+		err := rd.selectAllFiles(ctx, torrent.ID)
+		if err != nil {
+			log.Errorf("Error selecting files of real-debrid torrent %s: %s", torrent.Filename, err)
+		}
+	}
+	return nil
+}
+
+// transferStatus maps a torrent status to the premiumize.me transfer status used across the application
+func transferStatus(status string) string {
+	switch status {
+	case StatusDownloaded:
+		return clouddownloader.TransferStatusFinished
+	case StatusMagnetError, StatusError, StatusVirus, StatusDead:
+		return clouddownloader.TransferStatusError
+	case StatusDownloading, StatusCompressing, StatusUploading:
+		return clouddownloader.TransferStatusRunning
+	default:
+		return clouddownloader.TransferStatusQueued
+	}
+}
+
+func (rd *RealDebrid) CreateTransfer(ctx context.Context, filePath string, parentID string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Errorf("First try failed, waiting 1 second and trying to open file: %s again", filePath)
+		time.Sleep(1 * time.Second)
+		data, err = os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var res AddTorrentResponse
+	switch filepath.Ext(filePath) {
+	case ".magnet":
+		err = rd.postForm(ctx, "/torrents/addMagnet", url.Values{"magnet": {strings.TrimSpace(string(data))}}, &res)
+	case ".torrent":
+		err = rd.doRequest(ctx, http.MethodPut, "/torrents/addTorrent", "application/x-bittorrent", bytes.NewReader(data), &res)
+	default:
+		return ErrUnsupportedFileType
+	}
+	if err != nil {
+		return err
+	}
+	log.Tracef("Transfer created: %+v", res)
+
+	err = rd.owned.Add(res.ID)
+	if err != nil {
+		log.Errorf("Error recording real-debrid torrent %s as created by premiumizearr: %s", res.ID, err)
+	}
+
+	// Torrents usually wait for the file selection right away, otherwise StartTransfers selects them later
+	err = rd.selectAllFiles(ctx, res.ID)
+	if err != nil {
+		log.Debugf("Could not select files of torrent %s yet: %s", res.ID, err)
+	}
+
+	return nil
+}
+
+// DeleteTransfer deletes the torrent id, torrents not created by the application are refused
+func (rd *RealDebrid) DeleteTransfer(ctx context.Context, id string) error {
+	if !rd.owned.Contains(id) {
+		return fmt.Errorf("failed to delete transfer: %s, message: %w", id, ErrNotOwned)
+	}
+
+	err := rd.doRequest(ctx, http.MethodDelete, "/torrents/delete/"+url.PathEscape(id), "", nil, nil)
+	if err != nil && !errors.Is(err, clouddownloader.ErrNotFound) {
+		return fmt.Errorf("failed to delete transfer: %s, message: %w", id, err)
+	}
+
+	err = rd.owned.Remove(id)
+	if err != nil {
+		log.Errorf("Error forgetting deleted real-debrid torrent %s: %s", id, err)
+	}
+	return nil
+}
+
+// GetFolders returns the virtual downloads folder
+func (rd *RealDebrid) GetFolders(ctx context.Context) ([]Item, error) {
+	if rd.APIKey == "" {
+		return nil, ErrAPIKeyNotSet
+	}
+	return []Item{{ID: DownloadsFolderID, Name: DownloadsFolderID, Type: clouddownloader.ItemTypeFolder}}, nil
+}
+
+// ListFolder lists the downloaded torrents created by the application for the downloads folder and the files of a torrent otherwise.
+// Files are identified by their restricted hoster link which is unrestricted by GetItemDetails.
+func (rd *RealDebrid) ListFolder(ctx context.Context, folderID string) ([]Item, error) {
+	if folderID == DownloadsFolderID {
+		torrents, err := rd.getTorrents(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		items := make([]Item, 0)
+		for _, torrent := range torrents {
+			if torrent.Status != StatusDownloaded {
+				continue
+			}
+			items = append(items, Item{
+				ID:   torrent.ID,
+				Name: torrent.Filename,
+				Type: clouddownloader.ItemTypeFolder,
+				Size: torrent.Bytes,
+			})
+		}
+		return items, nil
+	}
+
+	info, err := rd.getTorrentInfo(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]TorrentFile, 0, len(info.Files))
+	for _, file := range info.Files {
+		if file.Selected == 1 {
+			selected = append(selected, file)
+		}
+	}
+
+	items := make([]Item, 0, len(info.Links))
+	for i, link := range info.Links {
+		item := Item{ID: link, Type: clouddownloader.ItemTypeFile}
+		// Links follow the order of the selected files unless real-debrid packed them into a single archive
+		if len(info.Links) == len(selected) {
+			item.Name = path.Base(selected[i].Path)
+			item.Size = selected[i].Bytes
+		} else if details, err := rd.GetItemDetails(ctx, link); err == nil {
+			item.Name = details.Name
+			item.Size = details.Size
+		} else {
+			return nil, fmt.Errorf("failed to get name of file %d of torrent %s: %w", i, info.Filename, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (rd *RealDebrid) CreateFolder(ctx context.Context, folderName string, parentID *string) (string, error) {
+	if parentID == nil && folderName == DownloadsFolderID {
+		return DownloadsFolderID, nil
+	}
+	return "", ErrNotSupported
+}
+
+// DeleteFolder deletes the torrent folderID
+func (rd *RealDebrid) DeleteFolder(ctx context.Context, folderID string) error {
+	return rd.DeleteTransfer(ctx, folderID)
+}
+
+func (rd *RealDebrid) MoveItem(ctx context.Context, itemID string, folderID string) error {
+	return ErrNotSupported
+}
+
+func (rd *RealDebrid) GenerateFileLink(ctx context.Context, ID string) (string, error) {
+	item, err := rd.GetItemDetails(ctx, ID)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf("File link created: %+v", item.Link)
+	return item.Link, nil
+}
+
+// GetItemDetails unrestricts the hoster link ID into a direct download link
+func (rd *RealDebrid) GetItemDetails(ctx context.Context, ID string) (Item, error) {
+	log.Trace("Unrestricting link: ", ID)
+	var res UnrestrictResponse
+	err := rd.postForm(ctx, "/unrestrict/link", url.Values{"link": {ID}}, &res)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return Item{
+		ID:       ID,
+		Name:     res.Filename,
+		Type:     clouddownloader.ItemTypeFile,
+		MimeType: res.MimeType,
+		Link:     res.Download,
+		Size:     res.Filesize,
+	}, nil
+}
@@ -0,0 +1,206 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+// fakeAPI is a Real-Debrid API holding torrents, it records the requests changing the account
+type fakeAPI struct {
+	mutex    sync.Mutex
+	torrents []Torrent
+	writes   []string
+}
+
+func (api *fakeAPI) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /torrents", func(w http.ResponseWriter, r *http.Request) {
+		api.mutex.Lock()
+		defer api.mutex.Unlock()
+		json.NewEncoder(w).Encode(api.torrents)
+	})
+	mux.HandleFunc("POST /torrents/addMagnet", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("magnet") == "" {
+			t.Error("addMagnet without magnet")
+		}
+		api.record(r)
+		json.NewEncoder(w).Encode(AddTorrentResponse{ID: "new"})
+	})
+	mux.HandleFunc("PUT /torrents/addTorrent", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		json.NewEncoder(w).Encode(AddTorrentResponse{ID: "new"})
+	})
+	mux.HandleFunc("POST /torrents/selectFiles/{id}", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /torrents/delete/{id}", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func (api *fakeAPI) record(r *http.Request) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.writes = append(api.writes, r.Method+" "+r.URL.Path)
+}
+
+func newTestClient(t *testing.T, handler http.Handler, ownedIDs ...string) (*RealDebrid, *clouddownloader.OwnedTransfers) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	owned, err := clouddownloader.LoadOwnedTransfers(filepath.Join(t.TempDir(), "realdebrid.transfers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ownedIDs {
+		owned.Add(id)
+	}
+
+	client := NewRealDebridClient("key", owned)
+	client.BaseURL = server.URL
+	return &client, owned
+}
+
+func TestListFolderOnlyListsOwnedTorrents(t *testing.T) {
+	api := &fakeAPI{torrents: []Torrent{
+		{ID: "own", Filename: "Own", Status: StatusDownloaded},
+		{ID: "user", Filename: "User", Status: StatusDownloaded},
+		{ID: "running", Filename: "Running", Status: StatusDownloading},
+	}}
+	client, _ := newTestClient(t, api.handler(t), "own", "running")
+
+	items, err := client.ListFolder(context.Background(), DownloadsFolderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != "own" {
+		t.Errorf("expected only the downloaded owned torrent, got %+v", items)
+	}
+
+	transfers, err := client.GetTransfers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 2 {
+		t.Errorf("expected the 2 owned transfers, got %+v", transfers)
+	}
+}
+
+func TestGetTransfersDoesNotChangeAccount(t *testing.T) {
+	api := &fakeAPI{torrents: []Torrent{
+		{ID: "own", Filename: "Own", Status: StatusWaitingFilesSelection},
+		{ID: "user", Filename: "User", Status: StatusWaitingFilesSelection},
+	}}
+	client, _ := newTestClient(t, api.handler(t), "own")
+
+	_, err := client.GetTransfers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.writes) != 0 {
+		t.Errorf("listing the transfers changed the account: %v", api.writes)
+	}
+
+	err = client.StartTransfers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.writes) != 1 || api.writes[0] != "POST /torrents/selectFiles/own" {
+		t.Errorf("expected the files of the owned torrent to be selected, got %v", api.writes)
+	}
+}
+
+func TestCreateTransferRecordsTorrent(t *testing.T) {
+	for _, name := range []string{"release.magnet", "release.torrent"} {
+		t.Run(name, func(t *testing.T) {
+			api := &fakeAPI{}
+			client, owned := newTestClient(t, api.handler(t))
+
+			filePath := filepath.Join(t.TempDir(), name)
+			err := os.WriteFile(filePath, []byte("magnet:?xt=urn:btih:0123"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.CreateTransfer(context.Background(), filePath, DownloadsFolderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !owned.Contains("new") {
+				t.Error("created torrent was not recorded")
+			}
+			if len(api.writes) != 2 || api.writes[1] != "POST /torrents/selectFiles/new" {
+				t.Errorf("expected the torrent to be added and its files selected, got %v", api.writes)
+			}
+		})
+	}
+}
+
+func TestDeleteFolderRefusesTorrentsOfUser(t *testing.T) {
+	api := &fakeAPI{}
+	client, owned := newTestClient(t, api.handler(t), "own")
+
+	err := client.DeleteFolder(context.Background(), "user")
+	if !errors.Is(err, ErrNotOwned) {
+		t.Errorf("expected ErrNotOwned deleting a torrent of the user, got %v", err)
+	}
+
+	err = client.DeleteFolder(context.Background(), "own")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.writes) != 1 || api.writes[0] != "DELETE /torrents/delete/own" {
+		t.Errorf("expected only the owned torrent to be deleted, got %v", api.writes)
+	}
+	if owned.Contains("own") {
+		t.Error("deleted torrent is still recorded")
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := []struct {
+		status    int
+		errorCode int
+		want      error
+	}{
+		{http.StatusServiceUnavailable, ErrorCodeTooManyActiveDownloads, clouddownloader.ErrLimitReached},
+		{http.StatusTooManyRequests, 0, clouddownloader.ErrRateLimited},
+		{http.StatusTooManyRequests, ErrorCodeTooManyRequests, clouddownloader.ErrRateLimited},
+		{http.StatusUnauthorized, ErrorCodeBadToken, clouddownloader.ErrAuth},
+		{http.StatusForbidden, 0, clouddownloader.ErrAuth},
+		{http.StatusNotFound, ErrorCodeResourceNotFound, clouddownloader.ErrNotFound},
+	}
+	for _, test := range tests {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "failed", ErrorCode: test.errorCode})
+		})
+		client, _ := newTestClient(t, handler)
+
+		_, err := client.GetTransfers(context.Background())
+		if !errors.Is(err, test.want) {
+			t.Errorf("status %d error code %d: expected %v, got %v", test.status, test.errorCode, test.want, err)
+		}
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	client, _ := newTestClient(t, handler)
+	_, err := client.GetTransfers(context.Background())
+	if err == nil || errors.Is(err, clouddownloader.ErrNotFound) || errors.Is(err, clouddownloader.ErrAuth) {
+		t.Errorf("expected an unclassified error, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package realdebrid
+
+import "github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+
+type Item = clouddownloader.Item
+type Transfer = clouddownloader.Transfer
+
+// Error codes of the REST API the application reacts to
+const (
+	ErrorCodeResourceNotFound       = 7
+	ErrorCodeBadToken               = 8
+	ErrorCodeTooManyActiveDownloads = 21
+	ErrorCodeTooManyRequests        = 34
+)
+
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	ErrorCode int    `json:"error_code"`
+}
+
+type AddTorrentResponse struct {
+	ID  string `json:"id"`
+	URI string `json:"uri"`
+}
+
+type Torrent struct {
+	ID       string   `json:"id"`
+	Filename string   `json:"filename"`
+	Hash     string   `json:"hash"`
+	Bytes    int64    `json:"bytes"`
+	Progress float64  `json:"progress"`
+	Status   string   `json:"status"`
+	Added    string   `json:"added"`
+	Links    []string `json:"links"`
+}
+
+type TorrentFile struct {
+	ID       int    `json:"id"`
+	Path     string `json:"path"`
+	Bytes    int64  `json:"bytes"`
+	Selected int    `json:"selected"`
+}
+
+type TorrentInfo struct {
+	Torrent
+	Files []TorrentFile `json:"files"`
+}
+
+type UnrestrictResponse struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType"`
+	Filesize int64  `json:"filesize"`
+	Link     string `json:"link"`
+	Download string `json:"download"`
+}
+
+// Torrent status values returned by the API
+const (
+	StatusMagnetError           = "magnet_error"
+	StatusMagnetConversion      = "magnet_conversion"
+	StatusWaitingFilesSelection = "waiting_files_selection"
+	StatusQueued                = "queued"
+	StatusDownloading           = "downloading"
+	StatusDownloaded            = "downloaded"
+	StatusError                 = "error"
+	StatusVirus                 = "virus"
+	StatusCompressing           = "compressing"
+	StatusUploading             = "uploading"
+	StatusDead                  = "dead"
+)
@@ -1,8 +1,8 @@
-package stringqueue
-
-import "sync"
-
-type StringQueue struct {
-	queue []string
-	mutex *sync.Mutex
-}
+package stringqueue
+
+import "sync"
+
+type StringQueue struct {
+	queue []string
+	mutex *sync.Mutex
+}
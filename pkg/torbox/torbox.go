@@ -0,0 +1,438 @@
+package torbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("subsystem", "torbox")
+
+const (
+	DefaultBaseURL = "https://api.torbox.app/v1/api"
+
+	// DownloadsFolderID is the virtual folder holding the downloads created by the application,
+	// TorBox has no folders so each download is presented as a folder containing its files
+	DownloadsFolderID = "arrDownloads"
+)
+
+var (
+	ErrAPIKeyNotSet        = errors.New("torbox API key not set")
+	ErrUnsupportedFileType = errors.New("torbox only supports .magnet, .torrent and .nzb files")
+	ErrNotSupported        = errors.New("operation not supported by torbox")
+	ErrInvalidID           = errors.New("invalid torbox id")
+	ErrNotOwned            = errors.New("download was not created by premiumizearr")
+)
+
+var _ clouddownloader.CloudDownloaderInterface = (*TorBox)(nil)
+
+// downloadKind holds the endpoints of torrent and usenet downloads, which otherwise behave the same
+type downloadKind struct {
+	name    string
+	path    string
+	idParam string
+	create  string
+	control string
+}
+
+var (
+	torrentKind = downloadKind{name: "torrent", path: "/torrents", idParam: "torrent_id", create: "/createtorrent", control: "/controltorrent"}
+	usenetKind  = downloadKind{name: "usenet", path: "/usenet", idParam: "usenet_id", create: "/createusenetdownload", control: "/controlusenetdownload"}
+	kinds       = []downloadKind{torrentKind, usenetKind}
+)
+
+type TorBox struct {
+	APIKey string
+	// BaseURL of the API, can be pointed at a fake server
+	BaseURL string
+	client  *http.Client
+	// owned are the downloads created by CreateTransfer, the only ones listed and deleted
+	owned *clouddownloader.OwnedTransfers
+}
+
+func NewTorBoxClient(APIKey string, owned *clouddownloader.OwnedTransfers) TorBox {
+	return TorBox{
+		APIKey:  APIKey,
+		BaseURL: DefaultBaseURL,
+		client:  &http.Client{Timeout: 60 * time.Second},
+		owned:   owned,
+	}
+}
+
+// doRequest sends an authenticated request to endpoint and decodes the data of the response into result if it is not nil
+func (tb *TorBox) doRequest(ctx context.Context, method string, endpoint string, query url.Values, contentType string, body io.Reader, result interface{}) error {
+	if tb.APIKey == "" {
+		return ErrAPIKeyNotSet
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(tb.BaseURL, "/") + endpoint)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+tb.APIKey)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	log.Tracef("TorBox request: %s %s", method, endpoint)
+	resp, err := tb.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res Response
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return fmt.Errorf("torbox error: %s: %w", resp.Status, err)
+	}
+
+	if !res.Success || resp.StatusCode >= 400 {
+		if res.Detail != "" {
+			err = fmt.Errorf("torbox error: %s (%s)", res.Detail, res.Error)
+		} else {
+			err = fmt.Errorf("torbox error: %s", resp.Status)
+		}
+		if sentinel := classifyError(resp.StatusCode, res.Error); sentinel != nil {
+			return fmt.Errorf("%w: %w", sentinel, err)
+		}
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(res.Data, result)
+}
+
+// classifyError maps an error response to the errors shared by the cloud downloaders, nil if it is not one of them
+func classifyError(statusCode int, code string) error {
+	switch {
+	case code == "ACTIVE_LIMIT":
+		return clouddownloader.ErrLimitReached
+	case code == "AUTH_ERROR" || code == "BAD_TOKEN" || statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return clouddownloader.ErrAuth
+	case statusCode == http.StatusTooManyRequests:
+		return clouddownloader.ErrRateLimited
+	case statusCode == http.StatusNotFound:
+		return clouddownloader.ErrNotFound
+	default:
+		return nil
+	}
+}
+
+// getDownloads lists the downloads of kind created by the application
+func (tb *TorBox) getDownloads(ctx context.Context, kind downloadKind) ([]Download, error) {
+	var downloads []Download
+	err := tb.doRequest(ctx, http.MethodGet, kind.path+"/mylist", url.Values{"bypass_cache": {"true"}}, "", nil, &downloads)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make([]Download, 0, len(downloads))
+	for _, download := range downloads {
+		if tb.owned.Contains(formatID(kind, download.ID)) {
+			owned = append(owned, download)
+		}
+	}
+	return owned, nil
+}
+
+func (tb *TorBox) getDownload(ctx context.Context, kind downloadKind, id int64) (Download, error) {
+	var download Download
+	query := url.Values{"bypass_cache": {"true"}, "id": {strconv.FormatInt(id, 10)}}
+	err := tb.doRequest(ctx, http.MethodGet, kind.path+"/mylist", query, "", nil, &download)
+	return download, err
+}
+
+// formatID prefixes ids with the kind as torrents and usenet downloads have separate id ranges
+func formatID(kind downloadKind, ids ...int64) string {
+	parts := []string{kind.name}
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ":")
+}
+
+// parseID splits an id created by formatID into its kind and numeric ids
+func parseID(ID string, count int) (downloadKind, []int64, error) {
+	parts := strings.Split(ID, ":")
+	if len(parts) != count+1 {
+		return downloadKind{}, nil, fmt.Errorf("%w: %s", ErrInvalidID, ID)
+	}
+
+	var kind downloadKind
+	for _, k := range kinds {
+		if k.name == parts[0] {
+			kind = k
+		}
+	}
+	if kind.name == "" {
+		return downloadKind{}, nil, fmt.Errorf("%w: %s", ErrInvalidID, ID)
+	}
+
+	ids := make([]int64, 0, count)
+	for _, part := range parts[1:] {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return downloadKind{}, nil, fmt.Errorf("%w: %s", ErrInvalidID, ID)
+		}
+		ids = append(ids, id)
+	}
+	return kind, ids, nil
+}
+
+func (tb *TorBox) GetTransfers(ctx context.Context) ([]Transfer, error) {
+	log.Trace("Getting transfers list from torbox")
+	transfers := make([]Transfer, 0)
+	for _, kind := range kinds {
+		downloads, err := tb.getDownloads(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, download := range downloads {
+			id := formatID(kind, download.ID)
+			transfers = append(transfers, Transfer{
+				ID:       id,
+				Name:     download.Name,
+				Message:  download.DownloadState,
+				Status:   transferStatus(download),
+				Progress: download.Progress,
+				FolderID: id,
+			})
+		}
+	}
+
+	log.Tracef("Received %d transfers", len(transfers))
+	return transfers, nil
+}
+
+// transferStatus maps the state of a download to the premiumize.me transfer status used across the application
+func transferStatus(download Download) string {
+	state := strings.ToLower(download.DownloadState)
+	switch {
+	case download.DownloadFinished && download.DownloadPresent:
+		return clouddownloader.TransferStatusFinished
+	case strings.Contains(state, "error") || strings.Contains(state, "failed"):
+		return clouddownloader.TransferStatusError
+	case state == "queued" || state == "metadl" || state == "checkingresumedata":
+		return clouddownloader.TransferStatusQueued
+	default:
+		return clouddownloader.TransferStatusRunning
+	}
+}
+
+func (tb *TorBox) CreateTransfer(ctx context.Context, filePath string, parentID string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Errorf("First try failed, waiting 1 second and trying to open file: %s again", filePath)
+		time.Sleep(1 * time.Second)
+		data, err = os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	kind := torrentKind
+	switch filepath.Ext(filePath) {
+	case ".magnet":
+		err = writer.WriteField("magnet", strings.TrimSpace(string(data)))
+	case ".torrent", ".nzb":
+		if filepath.Ext(filePath) == ".nzb" {
+			kind = usenetKind
+		}
+		var part io.Writer
+		part, err = writer.CreateFormFile("file", filepath.Base(filePath))
+		if err == nil {
+			_, err = part.Write(data)
+		}
+	default:
+		return ErrUnsupportedFileType
+	}
+	if err != nil {
+		return err
+	}
+	writer.Close()
+
+	var res CreateDownloadResponse
+	err = tb.doRequest(ctx, http.MethodPost, kind.path+kind.create, nil, writer.FormDataContentType(), body, &res)
+	if err != nil {
+		return err
+	}
+
+	log.Tracef("Transfer created: %+v", res)
+
+	id := res.TorrentID
+	if kind.name == usenetKind.name {
+		id = res.UsenetID
+	}
+	err = tb.owned.Add(formatID(kind, id))
+	if err != nil {
+		log.Errorf("Error recording torbox download %s as created by premiumizearr: %s", formatID(kind, id), err)
+	}
+	return nil
+}
+
+// DeleteTransfer deletes the download id, downloads not created by the application are refused
+func (tb *TorBox) DeleteTransfer(ctx context.Context, id string) error {
+	kind, ids, err := parseID(id, 1)
+	if err != nil {
+		return err
+	}
+	if !tb.owned.Contains(id) {
+		return fmt.Errorf("failed to delete transfer: %s, message: %w", id, ErrNotOwned)
+	}
+
+	control := ControlRequest{Operation: "delete"}
+	if kind.name == usenetKind.name {
+		control.UsenetID = &ids[0]
+	} else {
+		control.TorrentID = &ids[0]
+	}
+	body, err := json.Marshal(control)
+	if err != nil {
+		return err
+	}
+
+	err = tb.doRequest(ctx, http.MethodPost, kind.path+kind.control, nil, "application/json", bytes.NewReader(body), nil)
+	if err != nil && !errors.Is(err, clouddownloader.ErrNotFound) {
+		return fmt.Errorf("failed to delete transfer: %s, message: %w", id, err)
+	}
+
+	err = tb.owned.Remove(id)
+	if err != nil {
+		log.Errorf("Error forgetting deleted torbox download %s: %s", id, err)
+	}
+	return nil
+}
+
+// GetFolders returns the virtual downloads folder
+func (tb *TorBox) GetFolders(ctx context.Context) ([]Item, error) {
+	if tb.APIKey == "" {
+		return nil, ErrAPIKeyNotSet
+	}
+	return []Item{{ID: DownloadsFolderID, Name: DownloadsFolderID, Type: clouddownloader.ItemTypeFolder}}, nil
+}
+
+// ListFolder lists the finished downloads created by the application for the downloads folder and the files of a download otherwise
+func (tb *TorBox) ListFolder(ctx context.Context, folderID string) ([]Item, error) {
+	if folderID == DownloadsFolderID {
+		items := make([]Item, 0)
+		for _, kind := range kinds {
+			downloads, err := tb.getDownloads(ctx, kind)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, download := range downloads {
+				if transferStatus(download) != clouddownloader.TransferStatusFinished {
+					continue
+				}
+				items = append(items, Item{
+					ID:   formatID(kind, download.ID),
+					Name: download.Name,
+					Type: clouddownloader.ItemTypeFolder,
+					Size: download.Size,
+				})
+			}
+		}
+		return items, nil
+	}
+
+	kind, ids, err := parseID(folderID, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	download, err := tb.getDownload(ctx, kind, ids[0])
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(download.Files))
+	for _, file := range download.Files {
+		items = append(items, Item{
+			ID:       formatID(kind, download.ID, file.ID),
+			Name:     file.ShortName,
+			Type:     clouddownloader.ItemTypeFile,
+			MimeType: file.MimeType,
+			Size:     file.Size,
+		})
+	}
+	return items, nil
+}
+
+func (tb *TorBox) CreateFolder(ctx context.Context, folderName string, parentID *string) (string, error) {
+	if parentID == nil && folderName == DownloadsFolderID {
+		return DownloadsFolderID, nil
+	}
+	return "", ErrNotSupported
+}
+
+// DeleteFolder deletes the download folderID
+func (tb *TorBox) DeleteFolder(ctx context.Context, folderID string) error {
+	return tb.DeleteTransfer(ctx, folderID)
+}
+
+func (tb *TorBox) MoveItem(ctx context.Context, itemID string, folderID string) error {
+	return ErrNotSupported
+}
+
+func (tb *TorBox) GenerateFileLink(ctx context.Context, ID string) (string, error) {
+	item, err := tb.GetItemDetails(ctx, ID)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf("File link created: %+v", item.Link)
+	return item.Link, nil
+}
+
+// GetItemDetails requests a download link for the file ID, the size is only known from ListFolder
+func (tb *TorBox) GetItemDetails(ctx context.Context, ID string) (Item, error) {
+	kind, ids, err := parseID(ID, 2)
+	if err != nil {
+		return Item{}, err
+	}
+
+	log.Trace("Requesting download link for: ", ID)
+	query := url.Values{
+		"token":      {tb.APIKey},
+		kind.idParam: {strconv.FormatInt(ids[0], 10)},
+		"file_id":    {strconv.FormatInt(ids[1], 10)},
+	}
+	var link string
+	err = tb.doRequest(ctx, http.MethodGet, kind.path+"/requestdl", query, "", nil, &link)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return Item{
+		ID:   ID,
+		Type: clouddownloader.ItemTypeFile,
+		Link: link,
+	}, nil
+}
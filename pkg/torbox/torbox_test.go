@@ -0,0 +1,191 @@
+package torbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+// fakeAPI is a TorBox API holding torrents and usenet downloads, it records the requests changing the account
+type fakeAPI struct {
+	mutex    sync.Mutex
+	torrents []Download
+	usenet   []Download
+	writes   []string
+}
+
+func respond(w http.ResponseWriter, data interface{}) {
+	raw, _ := json.Marshal(data)
+	json.NewEncoder(w).Encode(Response{Success: true, Data: raw})
+}
+
+func (api *fakeAPI) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /torrents/mylist", func(w http.ResponseWriter, r *http.Request) {
+		api.mutex.Lock()
+		defer api.mutex.Unlock()
+		respond(w, api.torrents)
+	})
+	mux.HandleFunc("GET /usenet/mylist", func(w http.ResponseWriter, r *http.Request) {
+		api.mutex.Lock()
+		defer api.mutex.Unlock()
+		respond(w, api.usenet)
+	})
+	mux.HandleFunc("POST /torrents/createtorrent", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("magnet") == "" {
+			t.Error("createtorrent without magnet")
+		}
+		api.record(r.URL.Path)
+		respond(w, CreateDownloadResponse{TorrentID: 7})
+	})
+	mux.HandleFunc("POST /usenet/createusenetdownload", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := r.FormFile("file"); err != nil {
+			t.Errorf("createusenetdownload without file: %s", err)
+		}
+		api.record(r.URL.Path)
+		respond(w, CreateDownloadResponse{UsenetID: 8})
+	})
+	mux.HandleFunc("POST /torrents/controltorrent", func(w http.ResponseWriter, r *http.Request) {
+		var control ControlRequest
+		json.NewDecoder(r.Body).Decode(&control)
+		if control.TorrentID == nil || control.Operation != "delete" {
+			t.Errorf("unexpected control request %+v", control)
+		} else {
+			api.record(formatID(torrentKind, *control.TorrentID))
+		}
+		respond(w, nil)
+	})
+	return mux
+}
+
+func (api *fakeAPI) record(write string) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.writes = append(api.writes, write)
+}
+
+func newTestClient(t *testing.T, handler http.Handler, ownedIDs ...string) (*TorBox, *clouddownloader.OwnedTransfers) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	owned, err := clouddownloader.LoadOwnedTransfers(filepath.Join(t.TempDir(), "torbox.transfers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ownedIDs {
+		owned.Add(id)
+	}
+
+	client := NewTorBoxClient("key", owned)
+	client.BaseURL = server.URL
+	return &client, owned
+}
+
+func TestListFolderOnlyListsOwnedDownloads(t *testing.T) {
+	finished := Download{DownloadFinished: true, DownloadPresent: true, DownloadState: "completed"}
+	own, user, usenet := finished, finished, finished
+	own.ID, user.ID, usenet.ID = 1, 2, 1
+	api := &fakeAPI{
+		torrents: []Download{own, user, {ID: 3, DownloadState: "downloading"}},
+		usenet:   []Download{usenet},
+	}
+	client, _ := newTestClient(t, api.handler(t), "torrent:1", "torrent:3", "usenet:1")
+
+	items, err := client.ListFolder(context.Background(), DownloadsFolderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].ID != "torrent:1" || items[1].ID != "usenet:1" {
+		t.Errorf("expected only the finished owned downloads, got %+v", items)
+	}
+
+	transfers, err := client.GetTransfers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 3 {
+		t.Errorf("expected the 3 owned transfers, got %+v", transfers)
+	}
+}
+
+func TestCreateTransferRecordsDownload(t *testing.T) {
+	tests := map[string]string{
+		"release.magnet": "torrent:7",
+		"release.nzb":    "usenet:8",
+	}
+	for name, id := range tests {
+		t.Run(name, func(t *testing.T) {
+			api := &fakeAPI{}
+			client, owned := newTestClient(t, api.handler(t))
+
+			filePath := filepath.Join(t.TempDir(), name)
+			err := os.WriteFile(filePath, []byte("magnet:?xt=urn:btih:0123"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.CreateTransfer(context.Background(), filePath, DownloadsFolderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !owned.Contains(id) {
+				t.Errorf("created download %s was not recorded", id)
+			}
+		})
+	}
+}
+
+func TestDeleteFolderRefusesDownloadsOfUser(t *testing.T) {
+	api := &fakeAPI{}
+	client, owned := newTestClient(t, api.handler(t), "torrent:1")
+
+	err := client.DeleteFolder(context.Background(), "torrent:2")
+	if !errors.Is(err, ErrNotOwned) {
+		t.Errorf("expected ErrNotOwned deleting a download of the user, got %v", err)
+	}
+
+	err = client.DeleteFolder(context.Background(), "torrent:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.writes) != 1 || api.writes[0] != "torrent:1" {
+		t.Errorf("expected only the owned download to be deleted, got %v", api.writes)
+	}
+	if owned.Contains("torrent:1") {
+		t.Error("deleted download is still recorded")
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	tests := []struct {
+		status int
+		code   string
+		want   error
+	}{
+		{http.StatusBadRequest, "ACTIVE_LIMIT", clouddownloader.ErrLimitReached},
+		{http.StatusForbidden, "BAD_TOKEN", clouddownloader.ErrAuth},
+		{http.StatusUnauthorized, "", clouddownloader.ErrAuth},
+		{http.StatusTooManyRequests, "", clouddownloader.ErrRateLimited},
+		{http.StatusNotFound, "", clouddownloader.ErrNotFound},
+	}
+	for _, test := range tests {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			json.NewEncoder(w).Encode(Response{Error: test.code, Detail: "failed"})
+		})
+		client, _ := newTestClient(t, handler)
+
+		_, err := client.GetTransfers(context.Background())
+		if !errors.Is(err, test.want) {
+			t.Errorf("status %d code %q: expected %v, got %v", test.status, test.code, test.want, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package torbox
+
+import (
+	"encoding/json"
+
+	"github.com/ensingerphilipp/premiumizearr-nova/pkg/clouddownloader"
+)
+
+type Item = clouddownloader.Item
+type Transfer = clouddownloader.Transfer
+
+// Response is the envelope around every API response
+type Response struct {
+	Success bool            `json:"success"`
+	Error   string          `json:"error"`
+	Detail  string          `json:"detail"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type CreateDownloadResponse struct {
+	TorrentID int64  `json:"torrent_id"`
+	UsenetID  int64  `json:"usenetdownload_id"`
+	Name      string `json:"name"`
+	Hash      string `json:"hash"`
+}
+
+type DownloadFile struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	ShortName string `json:"short_name"`
+	Size      int64  `json:"size"`
+	MimeType  string `json:"mimetype"`
+}
+
+// Download is a torrent or usenet download, both share the same fields
+type Download struct {
+	ID               int64          `json:"id"`
+	Name             string         `json:"name"`
+	Size             int64          `json:"size"`
+	Progress         float64        `json:"progress"`
+	DownloadState    string         `json:"download_state"`
+	DownloadFinished bool           `json:"download_finished"`
+	DownloadPresent  bool           `json:"download_present"`
+	Files            []DownloadFile `json:"files"`
+}
+
+type ControlRequest struct {
+	TorrentID *int64 `json:"torrent_id,omitempty"`
+	UsenetID  *int64 `json:"usenet_id,omitempty"`
+	Operation string `json:"operation"`
+}
@@ -0,0 +1,46 @@
+import { CalculateAPIPath } from "./web_root";
+
+// One event stream is shared by all components, the browser reconnects it on its own
+let source = null;
+let connected = false;
+const listeners = new Set();
+
+function connect() {
+  source = new EventSource(CalculateAPIPath("api/events"));
+  source.onopen = () => {
+    connected = true;
+  };
+  source.onerror = () => {
+    connected = false;
+  };
+}
+
+// SubscribeEvents calls callback with the parsed event for every event of one of types,
+// it returns the function to unsubscribe
+export function SubscribeEvents(types, callback) {
+  if (source === null) {
+    connect();
+  }
+
+  const handlers = types.map((type) => {
+    const handler = (e) => callback(JSON.parse(e.data));
+    source.addEventListener(type, handler);
+    return [type, handler];
+  });
+  listeners.add(handlers);
+
+  return () => {
+    handlers.forEach(([type, handler]) => source.removeEventListener(type, handler));
+    listeners.delete(handlers);
+    if (listeners.size === 0) {
+      source.close();
+      source = null;
+      connected = false;
+    }
+  };
+}
+
+// EventsConnected reports whether state changes are currently pushed, polling is only needed otherwise
+export function EventsConnected() {
+  return connected;
+}